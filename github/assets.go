@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// releaseAsset is a local file that will be uploaded to a release
+type releaseAsset struct {
+	// Name is the name of the asset on the release
+	Name string
+	// Path is the path of the file relative to the exported directory
+	Path string
+	// Location is the absolute location of the file on disk
+	Location string
+	// ContentType is the detected media type of the file
+	ContentType string
+}
+
+// collectReleaseAssets walks the given directory and returns all the files it contains
+// as release assets. When flatten is true the asset name is the base name of the file,
+// otherwise the path elements are joined with an underscore.
+func collectReleaseAssets(root string, flatten bool) ([]releaseAsset, error) {
+	assets := []releaseAsset{}
+	names := map[string]string{}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		name := assetName(rel, flatten)
+
+		if existing, ok := names[name]; ok {
+			return fmt.Errorf("files %q and %q would both be uploaded as %q", existing, rel, name)
+		}
+		names[name] = rel
+
+		ct, err := detectContentType(p)
+		if err != nil {
+			return err
+		}
+
+		assets = append(assets, releaseAsset{
+			Name:        name,
+			Path:        rel,
+			Location:    p,
+			ContentType: ct,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release assets: %w", err)
+	}
+
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Name < assets[j].Name })
+
+	return assets, nil
+}
+
+// assetName returns the name of the release asset for the given slash separated relative path
+func assetName(rel string, flatten bool) string {
+	if flatten {
+		return path.Base(rel)
+	}
+
+	return strings.ReplaceAll(rel, "/", "_")
+}
+
+// detectContentType returns the media type for a file, the extension is used when it is known
+// otherwise the content of the file is sniffed
+func detectContentType(p string) (string, error) {
+	if ct := mime.TypeByExtension(filepath.Ext(p)); ct != "" {
+		return ct, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// uploadReleaseAsset uploads a single asset to the release with the given id
+func uploadReleaseAsset(ctx context.Context, client *github.Client, owner, repo string, id int64, a releaseAsset) error {
+	f, err := os.Open(a.Location)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	_, _, err = client.Repositories.UploadReleaseAsset(ctx, owner, repo, id, &github.UploadOptions{Name: a.Name, MediaType: a.ContentType}, f)
+	if err != nil {
+		return fmt.Errorf("failed to upload file %s: %w", a.Path, classifyError(err))
+	}
+
+	return nil
+}
+
+// syncReleaseAssets uploads the given assets to an existing release. Assets that have already been
+// uploaded with the same content are skipped, assets with the same name but different content, or
+// that were only partially uploaded, are deleted and uploaded again.
+func syncReleaseAssets(ctx context.Context, client *github.Client, owner, repo string, id int64, assets []releaseAsset) error {
+	existing, err := listReleaseAssets(ctx, client, owner, repo, id)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		if ra, ok := existing[a.Name]; ok {
+			same, err := assetMatches(ctx, client, owner, repo, ra, a)
+			if err != nil {
+				return err
+			}
+
+			if same {
+				log.Debug("Skipping unchanged release asset", "file", a.Path, "name", a.Name)
+				continue
+			}
+
+			_, err = client.Repositories.DeleteReleaseAsset(ctx, owner, repo, ra.GetID())
+			if err != nil {
+				return fmt.Errorf("failed to delete existing asset %s: %w", a.Name, classifyError(err))
+			}
+
+			log.Debug("Replacing release asset", "file", a.Path, "name", a.Name, "state", ra.GetState())
+		}
+
+		err := uploadReleaseAsset(ctx, client, owner, repo, id, a)
+		if err != nil {
+			return err
+		}
+
+		log.Debug("Added file to release", "file", a.Path, "name", a.Name)
+	}
+
+	return nil
+}
+
+// listReleaseAssets returns all the assets for a release keyed by name
+func listReleaseAssets(ctx context.Context, client *github.Client, owner, repo string, id int64) (map[string]*github.ReleaseAsset, error) {
+	assets := map[string]*github.ReleaseAsset{}
+	page := 0
+
+	for {
+		ras, resp, err := client.Repositories.ListReleaseAssets(ctx, owner, repo, id, &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list release assets: %w", classifyError(err))
+		}
+
+		for _, ra := range ras {
+			assets[ra.GetName()] = ra
+		}
+
+		page = resp.NextPage
+		if page == 0 {
+			break
+		}
+	}
+
+	return assets, nil
+}
+
+// assetMatches returns true when the release asset has been fully uploaded and has the same
+// content as the local file
+func assetMatches(ctx context.Context, client *github.Client, owner, repo string, ra *github.ReleaseAsset, a releaseAsset) (bool, error) {
+	// interrupted uploads leave the asset in the `starter` state, these always need to be replaced
+	if ra.GetState() != "uploaded" {
+		return false, nil
+	}
+
+	info, err := os.Stat(a.Location)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if int64(ra.GetSize()) != info.Size() {
+		return false, nil
+	}
+
+	rc, _, err := client.Repositories.DownloadReleaseAsset(ctx, owner, repo, ra.GetID(), http.DefaultClient)
+	if err != nil {
+		return false, fmt.Errorf("failed to download asset %s: %w", ra.GetName(), classifyError(err))
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, rc)
+	if err != nil {
+		return false, fmt.Errorf("failed to download asset %s: %w", ra.GetName(), err)
+	}
+
+	local, err := fileChecksum(a.Location)
+	if err != nil {
+		return false, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) == local, nil
+}
+
+// fileChecksum returns the hex encoded sha256 checksum of a file
+func fileChecksum(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
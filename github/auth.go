@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/oauth2"
+)
+
+// tokenSource returns the source of the tokens used to authenticate with the GitHub API,
+// either the static token set with WithToken or installation tokens for a GitHub App
+func (m *Github) tokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if m.Token != nil {
+		tkn, err := m.Token.Plaintext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tkn}), nil
+	}
+
+	if m.AppPrivateKey != nil {
+		pk, err := m.AppPrivateKey.Plaintext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parsePrivateKey([]byte(pk))
+		if err != nil {
+			return nil, err
+		}
+
+		// wrap the app source so that the installation token is only refreshed when it expires
+		return oauth2.ReuseTokenSource(nil, &appTokenSource{
+			ctx:            ctx,
+			m:              m,
+			appID:          m.AppID,
+			installationID: m.InstallationID,
+			key:            key,
+		}), nil
+	}
+
+	log.Error("GitHub token not set")
+	return nil, fmt.Errorf("GitHub token not set, please use the WithToken or WithAppAuth function to set the credentials")
+}
+
+// appTokenSource creates installation tokens for a GitHub App
+type appTokenSource struct {
+	ctx            context.Context
+	m              *Github
+	appID          int
+	installationID int
+	key            *rsa.PrivateKey
+}
+
+// Token exchanges a JWT signed with the app private key for an installation token
+func (a *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := appJWT(a.appID, a.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := a.m.newClient(&http.Client{Transport: &oauth2.Transport{
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt}),
+		Base:   newRetryTransport(nil),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	it, _, err := client.Apps.CreateInstallationToken(a.ctx, int64(a.installationID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token: %w", classifyError(err))
+	}
+
+	log.Debug("Created installation token", "installation", a.installationID, "expires", it.GetExpiresAt())
+
+	// refresh the token a minute before it expires so that it is not used while it is expiring
+	return &oauth2.Token{
+		AccessToken: it.GetToken(),
+		Expiry:      it.GetExpiresAt().Add(-time.Minute),
+	}, nil
+}
+
+// appJWT creates a JWT that authenticates as the GitHub App, the token is valid for 9 minutes
+// and the issued time is backdated to allow for clock drift
+func appJWT(appID int, key *rsa.PrivateKey, now time.Time) (string, error) {
+	return signJWT(key, "", map[string]any{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.Itoa(appID),
+	})
+}
+
+// signJWT creates a JWT containing the claims signed with RS256, the key id is added to the header when set
+func signJWT(key *rsa.PrivateKey, kid string, claims map[string]any) (string, error) {
+	h := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if kid != "" {
+		h["kid"] = kid
+	}
+
+	header, _ := json.Marshal(h)
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parsePrivateKey parses a PEM encoded PKCS1 or PKCS8 RSA private key
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key, expected PEM encoded key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key, expected RSA key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"main/internal/dagger"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// changelogSection is a section of the changelog containing the PRs with the given label
+type changelogSection struct {
+	Label string
+	Title string
+}
+
+// GenerateChangelog returns a Markdown changelog for the pull requests merged between fromTag and toRef,
+// the pull requests are grouped into sections by label. Sections are defined as `label=Title`, i.e.
+// `minor=Features`, and are written in the order they are defined, pull requests that do not match a section
+// are added to `Other Changes`. Pull requests with any of the excludeLabels are not included.
+// When fromTag is empty all the pull requests for the commits reachable from toRef are included.
+func (m *Github) GenerateChangelog(
+	ctx context.Context,
+	owner,
+	repo string,
+	// +optional
+	fromTag string,
+	toRef string,
+	// +optional
+	title string,
+	// +optional
+	// +default=["major=Breaking Changes", "minor=Features", "patch=Bug Fixes"]
+	sections []string,
+	// +optional
+	excludeLabels []string,
+) (*dagger.File, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := parseChangelogSections(sections)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := listCommitsSince(ctx, client, owner, repo, fromTag, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	prs, err := mergedPullRequests(ctx, client, owner, repo, commits)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("Generating changelog", "from", fromTag, "to", toRef, "commits", len(commits), "prs", len(prs))
+
+	if title == "" {
+		title = toRef
+	}
+
+	md := renderChangelog(title, prs, cs, excludeLabels)
+
+	return dag.Directory().WithNewFile("CHANGELOG.md", md).File("CHANGELOG.md"), nil
+}
+
+// mergedPullRequests returns the merged PRs associated with the commits
+func mergedPullRequests(ctx context.Context, client *github.Client, owner, repo string, commits []*github.RepositoryCommit) ([]*github.PullRequest, error) {
+	seen := map[int]bool{}
+	prs := []*github.PullRequest{}
+
+	for _, c := range commits {
+		p, err := listPullRequestsWithCommit(ctx, client, owner, repo, c.GetSHA())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pr := range p {
+			if pr.MergedAt == nil || seen[pr.GetNumber()] {
+				continue
+			}
+
+			seen[pr.GetNumber()] = true
+			prs = append(prs, pr)
+		}
+	}
+
+	return prs, nil
+}
+
+// parseChangelogSections parses section definitions in the form `label=Title`
+func parseChangelogSections(sections []string) ([]changelogSection, error) {
+	cs := []changelogSection{}
+
+	for _, s := range sections {
+		label, title, ok := strings.Cut(s, "=")
+		if !ok || label == "" || title == "" {
+			return nil, fmt.Errorf("invalid section %q, sections must be in the form label=Title", s)
+		}
+
+		cs = append(cs, changelogSection{Label: label, Title: title})
+	}
+
+	return cs, nil
+}
+
+// renderChangelog writes the PRs as Markdown grouped by section, a PR is added to the first section that
+// matches one of its labels
+func renderChangelog(title string, prs []*github.PullRequest, sections []changelogSection, exclude []string) string {
+	sort.Slice(prs, func(i, j int) bool { return prs[i].GetNumber() < prs[j].GetNumber() })
+
+	grouped := map[string][]*github.PullRequest{}
+	other := []*github.PullRequest{}
+
+	for _, pr := range prs {
+		if hasAnyLabel(pr, exclude) {
+			continue
+		}
+
+		matched := false
+		for _, s := range sections {
+			if hasAnyLabel(pr, []string{s.Label}) {
+				grouped[s.Title] = append(grouped[s.Title], pr)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			other = append(other, pr)
+		}
+	}
+
+	md := &strings.Builder{}
+	fmt.Fprintf(md, "## %s\n", title)
+
+	write := func(title string, prs []*github.PullRequest) {
+		if len(prs) == 0 {
+			return
+		}
+
+		fmt.Fprintf(md, "\n### %s\n\n", title)
+		for _, pr := range prs {
+			fmt.Fprintf(md, "- %s (#%d) @%s\n", pr.GetTitle(), pr.GetNumber(), pr.GetUser().GetLogin())
+		}
+	}
+
+	for _, s := range sections {
+		write(s.Title, grouped[s.Title])
+		delete(grouped, s.Title)
+	}
+
+	write("Other Changes", other)
+
+	return md.String()
+}
+
+// hasAnyLabel returns true if the PR has any of the labels
+func hasAnyLabel(pr *github.PullRequest, labels []string) bool {
+	for _, l := range pr.Labels {
+		for _, n := range labels {
+			if l.GetName() == n {
+				return true
+			}
+		}
+	}
+
+	return false
+}
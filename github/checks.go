@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"main/internal/dagger"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// maxAnnotations is the maximum number of annotations the checks API accepts in a single request
+const maxAnnotations = 50
+
+// CreateCheckRun creates a check run for the given commit SHA and returns the id of the check run.
+// Annotations are created for the failed tests in the reports, reports can be JUnit XML or Cucumber JSON files.
+// When a conclusion is set the check run is marked as completed.
+func (m *Github) CreateCheckRun(
+	ctx context.Context,
+	owner,
+	repo,
+	headSHA,
+	name string,
+	// +optional
+	status string,
+	// +optional
+	conclusion string,
+	// +optional
+	title string,
+	// +optional
+	summary string,
+	// +optional
+	reports []*dagger.File,
+	// +optional
+	detailsURL string,
+) (int, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	annotations, err := annotationsFromReports(ctx, reports)
+	if err != nil {
+		return 0, err
+	}
+
+	batches := batchAnnotations(annotations)
+
+	opts := github.CreateCheckRunOptions{
+		Name:    name,
+		HeadSHA: headSHA,
+		Output:  checkRunOutput(name, title, summary, annotations, batches[0]),
+	}
+
+	if detailsURL != "" {
+		opts.DetailsURL = &detailsURL
+	}
+
+	opts.Status, opts.Conclusion, opts.CompletedAt = checkRunStatus(status, conclusion)
+
+	cr, _, err := client.Checks.CreateCheckRun(ctx, owner, repo, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create check run: %w", classifyError(err))
+	}
+
+	log.Debug("Created check run", "id", cr.GetID(), "annotations", len(annotations))
+
+	// the remaining annotations are added by updating the check run
+	err = addAnnotations(ctx, client, owner, repo, cr.GetID(), name, checkRunOutput(name, title, summary, annotations, nil), batches[1:])
+	if err != nil {
+		return 0, err
+	}
+
+	return int(cr.GetID()), nil
+}
+
+// UpdateCheckRun updates the status, conclusion and output of an existing check run, annotations for the
+// failed tests in the reports are added to any existing annotations.
+func (m *Github) UpdateCheckRun(
+	ctx context.Context,
+	owner,
+	repo string,
+	checkRunID int,
+	// +optional
+	name string,
+	// +optional
+	status string,
+	// +optional
+	conclusion string,
+	// +optional
+	title string,
+	// +optional
+	summary string,
+	// +optional
+	reports []*dagger.File,
+) error {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	id := int64(checkRunID)
+
+	// the name is required when updating a check run
+	if name == "" {
+		cr, _, err := client.Checks.GetCheckRun(ctx, owner, repo, id)
+		if err != nil {
+			return fmt.Errorf("failed to get check run: %w", classifyError(err))
+		}
+
+		name = cr.GetName()
+	}
+
+	annotations, err := annotationsFromReports(ctx, reports)
+	if err != nil {
+		return err
+	}
+
+	batches := batchAnnotations(annotations)
+
+	opts := github.UpdateCheckRunOptions{
+		Name:   name,
+		Output: checkRunOutput(name, title, summary, annotations, batches[0]),
+	}
+
+	opts.Status, opts.Conclusion, opts.CompletedAt = checkRunStatus(status, conclusion)
+
+	_, _, err = client.Checks.UpdateCheckRun(ctx, owner, repo, id, opts)
+	if err != nil {
+		return fmt.Errorf("failed to update check run: %w", classifyError(err))
+	}
+
+	log.Debug("Updated check run", "id", id, "annotations", len(annotations))
+
+	return addAnnotations(ctx, client, owner, repo, id, name, checkRunOutput(name, title, summary, annotations, nil), batches[1:])
+}
+
+// addAnnotations updates the check run once for each batch of annotations
+func addAnnotations(ctx context.Context, client *github.Client, owner, repo string, id int64, name string, output *github.CheckRunOutput, batches [][]*github.CheckRunAnnotation) error {
+	for _, b := range batches {
+		o := *output
+		o.Annotations = b
+
+		_, _, err := client.Checks.UpdateCheckRun(ctx, owner, repo, id, github.UpdateCheckRunOptions{
+			Name:   name,
+			Output: &o,
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to add annotations to check run: %w", classifyError(err))
+		}
+
+		log.Debug("Added annotations to check run", "id", id, "annotations", len(b))
+	}
+
+	return nil
+}
+
+// checkRunStatus returns the status, conclusion and completed time for a check run, setting a
+// conclusion completes the check run
+func checkRunStatus(status, conclusion string) (*string, *string, *github.Timestamp) {
+	if conclusion != "" {
+		return github.String("completed"), &conclusion, &github.Timestamp{Time: time.Now()}
+	}
+
+	if status != "" {
+		return &status, nil, nil
+	}
+
+	return nil, nil, nil
+}
+
+// checkRunOutput returns the output for a check run, the output is only set when there is a
+// title, summary or annotations
+func checkRunOutput(name, title, summary string, all, batch []*github.CheckRunAnnotation) *github.CheckRunOutput {
+	if title == "" && summary == "" && len(all) == 0 {
+		return nil
+	}
+
+	if title == "" {
+		title = name
+	}
+
+	if summary == "" {
+		summary = fmt.Sprintf("%d failed tests", len(all))
+	}
+
+	return &github.CheckRunOutput{
+		Title:       &title,
+		Summary:     &summary,
+		Annotations: batch,
+	}
+}
+
+// batchAnnotations splits the annotations into batches that can be sent in a single request,
+// there is always at least one batch
+func batchAnnotations(annotations []*github.CheckRunAnnotation) [][]*github.CheckRunAnnotation {
+	batches := [][]*github.CheckRunAnnotation{nil}
+
+	for i := 0; i < len(annotations); i += maxAnnotations {
+		end := min(i+maxAnnotations, len(annotations))
+
+		if i == 0 {
+			batches[0] = annotations[i:end]
+			continue
+		}
+
+		batches = append(batches, annotations[i:end])
+	}
+
+	return batches
+}
+
+// annotationsFromReports returns failure annotations for the test reports, the format of each
+// report is detected from its contents
+func annotationsFromReports(ctx context.Context, reports []*dagger.File) ([]*github.CheckRunAnnotation, error) {
+	annotations := []*github.CheckRunAnnotation{}
+
+	for _, r := range reports {
+		data, err := r.Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report: %w", err)
+		}
+
+		var a []*github.CheckRunAnnotation
+
+		trimmed := strings.TrimSpace(data)
+
+		switch {
+		case strings.HasPrefix(trimmed, "<"):
+			a, err = parseJUnit([]byte(data))
+		case strings.HasPrefix(trimmed, "["):
+			a, err = parseCucumber([]byte(data))
+		default:
+			err = fmt.Errorf("unknown report format, expected JUnit XML or Cucumber JSON")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// GitHub rejects annotations without a path, failures that can not be linked to a file are skipped
+		for _, an := range a {
+			if an.GetPath() == "" {
+				log.Warn("Skipping failure without a file path", "test", an.GetTitle())
+				continue
+			}
+
+			annotations = append(annotations, an)
+		}
+	}
+
+	return annotations, nil
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	File      string          `xml:"file,attr"`
+	Suites    []junitSuite    `xml:"testsuite"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name     string         `xml:"name,attr"`
+	File     string         `xml:"file,attr"`
+	Line     int            `xml:"line,attr"`
+	Failures []junitFailure `xml:"failure"`
+	Errors   []junitFailure `xml:"error"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnit returns annotations for the failed and errored test cases in a JUnit XML report,
+// the root element can be either testsuites or testsuite
+func parseJUnit(data []byte) ([]*github.CheckRunAnnotation, error) {
+	root := junitSuite{}
+	err := xml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+	}
+
+	annotations := []*github.CheckRunAnnotation{}
+
+	var walk func(s junitSuite, file string)
+	walk = func(s junitSuite, file string) {
+		if s.File != "" {
+			file = s.File
+		}
+
+		for _, tc := range s.TestCases {
+			for _, f := range append(tc.Failures, tc.Errors...) {
+				p := tc.File
+				if p == "" {
+					p = file
+				}
+
+				msg := f.Message
+				if msg == "" {
+					msg = strings.TrimSpace(f.Text)
+				}
+
+				annotations = append(annotations, failureAnnotation(p, tc.Line, tc.Name, msg, f.Text))
+			}
+		}
+
+		for _, c := range s.Suites {
+			walk(c, file)
+		}
+	}
+
+	walk(root, "")
+
+	return annotations, nil
+}
+
+type cucumberFeature struct {
+	URI      string            `json:"uri"`
+	Name     string            `json:"name"`
+	Elements []cucumberElement `json:"elements"`
+}
+
+type cucumberElement struct {
+	Name  string         `json:"name"`
+	Line  int            `json:"line"`
+	Steps []cucumberStep `json:"steps"`
+}
+
+type cucumberStep struct {
+	Keyword string `json:"keyword"`
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	Result  struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"result"`
+}
+
+// parseCucumber returns annotations for the failed steps in a Cucumber JSON report
+func parseCucumber(data []byte) ([]*github.CheckRunAnnotation, error) {
+	features := []cucumberFeature{}
+	err := json.Unmarshal(data, &features)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Cucumber report: %w", err)
+	}
+
+	annotations := []*github.CheckRunAnnotation{}
+
+	for _, f := range features {
+		for _, e := range f.Elements {
+			for _, s := range e.Steps {
+				if s.Result.Status != "failed" {
+					continue
+				}
+
+				title := fmt.Sprintf("%s: %s", f.Name, e.Name)
+				msg := fmt.Sprintf("%s%s", s.Keyword, s.Name)
+
+				annotations = append(annotations, failureAnnotation(f.URI, s.Line, title, msg, s.Result.ErrorMessage))
+			}
+		}
+	}
+
+	return annotations, nil
+}
+
+// failureAnnotation creates a failure annotation for a single line
+func failureAnnotation(path string, line int, title, message, details string) *github.CheckRunAnnotation {
+	if line < 1 {
+		line = 1
+	}
+
+	if message == "" {
+		message = "Test failed"
+	}
+
+	a := &github.CheckRunAnnotation{
+		Path:            &path,
+		StartLine:       &line,
+		EndLine:         &line,
+		AnnotationLevel: github.String("failure"),
+		Message:         &message,
+		Title:           &title,
+	}
+
+	if d := strings.TrimSpace(details); d != "" {
+		a.RawDetails = &d
+	}
+
+	return a
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// UpsertPullRequestComment creates a comment on a pull request or updates the comment previously created with the
+// same key, this allows information like test results to be posted on every run without adding duplicate comments.
+// The key is stored in the comment as a hidden marker. Returns the id of the comment.
+func (m *Github) UpsertPullRequestComment(
+	ctx context.Context,
+	owner,
+	repo string,
+	number int,
+	key,
+	body string,
+) (int, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return upsertComment(ctx, client, owner, repo, number, key, body)
+}
+
+// UpsertCommitPullRequestComment creates or updates a comment with the given key on the pull request associated
+// with the commit SHA. If there are multiple PRs associated with the commit, the latest PR is used.
+// Returns the id of the comment, or 0 when there is no PR associated with the commit.
+func (m *Github) UpsertCommitPullRequestComment(
+	ctx context.Context,
+	owner,
+	repo,
+	sha,
+	key,
+	body string,
+) (int, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	prs, err := listPullRequestsWithCommit(ctx, client, owner, repo, sha)
+	if err != nil {
+		return 0, err
+	}
+
+	number := 0
+	for _, pr := range prs {
+		if pr.GetNumber() > number {
+			number = pr.GetNumber()
+		}
+	}
+
+	if number == 0 {
+		log.Debug("No PRs associated with commit")
+		return 0, nil
+	}
+
+	return upsertComment(ctx, client, owner, repo, number, key, body)
+}
+
+// commentMarker returns the hidden marker used to find the comment for a key
+func commentMarker(key string) string {
+	return fmt.Sprintf("<!-- daggerverse-github-comment: %s -->", key)
+}
+
+// upsertComment updates the comment containing the marker for the key or creates a new comment
+func upsertComment(ctx context.Context, client *github.Client, owner, repo string, number int, key, body string) (int, error) {
+	marker := commentMarker(key)
+	body = marker + "\n" + body
+
+	existing, err := findComment(ctx, client, owner, repo, number, marker)
+	if err != nil {
+		return 0, err
+	}
+
+	if existing != nil {
+		c, _, err := client.Issues.EditComment(ctx, owner, repo, existing.GetID(), &github.IssueComment{Body: &body})
+		if err != nil {
+			return 0, fmt.Errorf("failed to update comment: %w", classifyError(err))
+		}
+
+		log.Debug("Updated comment", "pr", number, "key", key, "comment", c.GetID())
+
+		return int(c.GetID()), nil
+	}
+
+	c, _, err := client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create comment: %w", classifyError(err))
+	}
+
+	log.Debug("Created comment", "pr", number, "key", key, "comment", c.GetID())
+
+	return int(c.GetID()), nil
+}
+
+// findComment returns the first comment on the issue or pull request starting with the marker, comments
+// that quote the marker of another comment are not matched
+func findComment(ctx context.Context, client *github.Client, owner, repo string, number int, marker string) (*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, owner, repo, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list comments: %w", classifyError(err))
+		}
+
+		for _, c := range comments {
+			if strings.HasPrefix(c.GetBody(), marker) {
+				return c, nil
+			}
+		}
+
+		opts.Page = resp.NextPage
+		if opts.Page == 0 {
+			break
+		}
+	}
+
+	return nil, nil
+}
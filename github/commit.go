@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"main/internal/dagger"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// CommitDirectory commits the contents of a directory to a repository at the given path as a single commit
+// using the Git Data API. If the branch does not exist it is created from baseRef, or the default branch of
+// the repository when baseRef is not set. When deleteMissing is set, files in the repository under commitPath
+// that do not exist in the directory are deleted. Returns the SHA of the new commit, if the directory does not
+// change the contents of the branch no commit is created and the SHA of the current head is returned.
+func (m *Github) CommitDirectory(
+	ctx context.Context,
+	owner,
+	repo,
+	commiterName,
+	commiterEmail,
+	message string,
+	directory *dagger.Directory,
+	// +optional
+	commitPath string,
+	// +optional
+	branch string,
+	// +optional
+	baseRef string,
+	// +optional
+	deleteMissing bool,
+) (string, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "commit-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = directory.Export(ctx, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to export directory: %w", err)
+	}
+
+	return commitTree(ctx, client, commitOptions{
+		Owner:         owner,
+		Repo:          repo,
+		Branch:        branch,
+		BaseRef:       baseRef,
+		Message:       message,
+		Author:        &github.CommitAuthor{Name: &commiterName, Email: &commiterEmail},
+		Root:          dir,
+		CommitPath:    commitPath,
+		DeleteMissing: deleteMissing,
+		Signer:        m.gitSigner(ctx),
+	})
+}
+
+// commitOptions defines a commit created with the Git Data API
+type commitOptions struct {
+	Owner string
+	Repo  string
+	// Branch to commit to, when empty the default branch of the repository is used
+	Branch string
+	// BaseRef is the ref the branch is created from when it does not exist
+	BaseRef string
+	Message string
+	Author  *github.CommitAuthor
+	// Root is the local directory containing the files to commit
+	Root string
+	// CommitPath is the path in the repository the files are committed to
+	CommitPath string
+	// DeleteMissing deletes files under CommitPath that do not exist in Root
+	DeleteMissing bool
+	// Signer signs the commit, when nil the commit is not signed
+	Signer github.MessageSigner
+}
+
+// commitTree creates a commit containing all the files in the local directory, returns the SHA of the commit
+func commitTree(ctx context.Context, client *github.Client, opts commitOptions) (string, error) {
+	if opts.Branch == "" || opts.BaseRef == "" {
+		r, _, err := client.Repositories.Get(ctx, opts.Owner, opts.Repo)
+		if err != nil {
+			return "", fmt.Errorf("failed to get repository: %w", classifyError(err))
+		}
+
+		if opts.Branch == "" {
+			opts.Branch = r.GetDefaultBranch()
+		}
+
+		if opts.BaseRef == "" {
+			opts.BaseRef = r.GetDefaultBranch()
+		}
+	}
+
+	// find the head of the branch, if the branch does not exist use the base ref
+	exists := true
+	ref, _, err := client.Git.GetRef(ctx, opts.Owner, opts.Repo, "heads/"+opts.Branch)
+	if err != nil && !isNotFound(err) {
+		return "", fmt.Errorf("failed to get branch: %w", classifyError(err))
+	}
+
+	var parent string
+	if err == nil {
+		parent = ref.GetObject().GetSHA()
+	} else {
+		exists = false
+
+		parent, _, err = client.Repositories.GetCommitSHA1(ctx, opts.Owner, opts.Repo, opts.BaseRef, "")
+		if err != nil {
+			return "", fmt.Errorf("failed to get base ref %s: %w", opts.BaseRef, classifyError(err))
+		}
+
+		log.Debug("Branch does not exist, creating from base", "branch", opts.Branch, "base", opts.BaseRef, "sha", parent)
+	}
+
+	pc, _, err := client.Git.GetCommit(ctx, opts.Owner, opts.Repo, parent)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit: %w", classifyError(err))
+	}
+
+	baseTree := pc.GetTree().GetSHA()
+
+	entries, err := treeEntries(ctx, client, opts, baseTree)
+	if err != nil {
+		return "", err
+	}
+
+	tree, _, err := client.Git.CreateTree(ctx, opts.Owner, opts.Repo, baseTree, entries)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", classifyError(err))
+	}
+
+	// nothing has changed, there is no need to create an empty commit
+	if tree.GetSHA() == baseTree && exists {
+		log.Debug("No changes to commit", "branch", opts.Branch, "sha", parent)
+		return parent, nil
+	}
+
+	var co *github.CreateCommitOptions
+	author := opts.Author
+
+	// the signed payload contains the author date, so the date must be set rather than left to GitHub
+	if opts.Signer != nil {
+		co = &github.CreateCommitOptions{Signer: opts.Signer}
+		author = &github.CommitAuthor{Name: opts.Author.Name, Email: opts.Author.Email, Date: signingTime()}
+	}
+
+	c, _, err := client.Git.CreateCommit(ctx, opts.Owner, opts.Repo, &github.Commit{
+		Message:   &opts.Message,
+		Tree:      &github.Tree{SHA: tree.SHA},
+		Parents:   []*github.Commit{{SHA: &parent}},
+		Author:    author,
+		Committer: author,
+	}, co)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", classifyError(err))
+	}
+
+	newRef := &github.Reference{
+		Ref:    github.String("refs/heads/" + opts.Branch),
+		Object: &github.GitObject{SHA: c.SHA},
+	}
+
+	if exists {
+		_, _, err = client.Git.UpdateRef(ctx, opts.Owner, opts.Repo, newRef, false)
+	} else {
+		_, _, err = client.Git.CreateRef(ctx, opts.Owner, opts.Repo, newRef)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to update branch %s: %w", opts.Branch, classifyError(err))
+	}
+
+	log.Debug("Created commit", "branch", opts.Branch, "sha", c.GetSHA())
+
+	return c.GetSHA(), nil
+}
+
+// treeEntries creates blobs for the files in the local directory and returns the tree entries for the commit,
+// when DeleteMissing is set entries with no SHA are added for files that are no longer present
+func treeEntries(ctx context.Context, client *github.Client, opts commitOptions, baseTree string) ([]*github.TreeEntry, error) {
+	entries := []*github.TreeEntry{}
+	paths := map[string]bool{}
+	prefix := strings.Trim(opts.CommitPath, "/")
+
+	err := filepath.WalkDir(opts.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(opts.Root, p)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		blob, _, err := client.Git.CreateBlob(ctx, opts.Owner, opts.Repo, &github.Blob{
+			Content:  github.String(base64.StdEncoding.EncodeToString(data)),
+			Encoding: github.String("base64"),
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to create blob for %s: %w", rel, classifyError(err))
+		}
+
+		mode := "100644"
+		if info.Mode()&0111 != 0 {
+			mode = "100755"
+		}
+
+		repoPath := path.Join(prefix, filepath.ToSlash(rel))
+		paths[repoPath] = true
+
+		entries = append(entries, &github.TreeEntry{
+			Path: &repoPath,
+			Mode: &mode,
+			Type: github.String("blob"),
+			SHA:  blob.SHA,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files: %w", err)
+	}
+
+	if !opts.DeleteMissing {
+		return entries, nil
+	}
+
+	current, _, err := client.Git.GetTree(ctx, opts.Owner, opts.Repo, baseTree, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", classifyError(err))
+	}
+
+	if current.GetTruncated() {
+		return nil, fmt.Errorf("repository tree is too large to determine deleted files")
+	}
+
+	for _, e := range current.Entries {
+		if e.GetType() != "blob" || paths[e.GetPath()] {
+			continue
+		}
+
+		if prefix != "" && !strings.HasPrefix(e.GetPath(), prefix+"/") {
+			continue
+		}
+
+		log.Debug("Deleting file", "path", e.GetPath())
+
+		entries = append(entries, &github.TreeEntry{
+			Path: e.Path,
+			Mode: e.Mode,
+			Type: e.Type,
+		})
+	}
+
+	return entries, nil
+}
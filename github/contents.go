@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"main/internal/dagger"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// GetFile returns a file from a repository at the given ref, if the ref is not set the default branch is used.
+// Files larger than the 1MB limit of the contents API are downloaded using the blob API.
+func (m *Github) GetFile(
+	ctx context.Context,
+	owner,
+	repo,
+	path string,
+	// +optional
+	ref string,
+) (*dagger.File, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fc, _, _, err := client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", classifyError(err))
+	}
+
+	if fc == nil {
+		return nil, fmt.Errorf("%s is a directory, use GetDirectory to fetch directories", path)
+	}
+
+	if fc.GetType() == "submodule" {
+		return nil, fmt.Errorf("%s is a submodule", path)
+	}
+
+	var data []byte
+
+	// the content is not returned for files over 1MB
+	if fc.GetEncoding() == "none" || (fc.Content == nil && fc.GetSize() > 0) {
+		data, _, err = client.Git.GetBlobRaw(ctx, owner, repo, fc.GetSHA())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blob for %s: %w", path, classifyError(err))
+		}
+	} else {
+		c, err := fc.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode file %s: %w", path, err)
+		}
+
+		data = []byte(c)
+	}
+
+	// the file is written using its name in the repository so the returned file keeps the same name
+	dir, err := os.MkdirTemp(".", "repository-file-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	name := fc.GetName()
+	err = os.WriteFile(filepath.Join(dir, name), data, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	log.Debug("Fetched file", "path", path, "ref", ref, "size", len(data))
+
+	d, err := dag.CurrentModule().Workdir(dir).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.File(name), nil
+}
+
+// GetDirectory returns a directory from a repository at the given ref, if the ref is not set the default branch
+// is used. The path is empty or `.` for the root of the repository. Files are downloaded using the blob API so
+// there is no limit on the size of the files, the execute permission of files is kept and submodules are skipped.
+func (m *Github) GetDirectory(
+	ctx context.Context,
+	owner,
+	repo string,
+	// +optional
+	path string,
+	// +optional
+	ref string,
+) (*dagger.Directory, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	sha, _, err := client.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ref %s: %w", ref, classifyError(err))
+	}
+
+	c, _, err := client.Git.GetCommit(ctx, owner, repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", classifyError(err))
+	}
+
+	treeSHA, err := findTree(ctx, client, owner, repo, c.GetTree().GetSHA(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, _, err := client.Git.GetTree(ctx, owner, repo, treeSHA, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", classifyError(err))
+	}
+
+	if tree.GetTruncated() {
+		return nil, fmt.Errorf("directory %s contains too many files to fetch, fetch a subdirectory instead", path)
+	}
+
+	dir, err := os.MkdirTemp(".", "repository-directory-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	files := 0
+	for _, e := range tree.Entries {
+		// directories are created with the files they contain and submodules are commits rather than blobs
+		if e.GetType() != "blob" {
+			continue
+		}
+
+		err := writeBlob(ctx, client, owner, repo, e, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		files++
+	}
+
+	log.Debug("Fetched directory", "path", path, "ref", ref, "sha", sha, "files", files)
+
+	return dag.CurrentModule().Workdir(dir).Sync(ctx)
+}
+
+// findTree returns the SHA of the tree at the path by walking down from the root tree
+func findTree(ctx context.Context, client *github.Client, owner, repo, root, p string) (string, error) {
+	sha := root
+
+	p = strings.Trim(filepath.ToSlash(filepath.Clean("/"+p)), "/")
+	if p == "" {
+		return sha, nil
+	}
+
+	for _, name := range strings.Split(p, "/") {
+		tree, _, err := client.Git.GetTree(ctx, owner, repo, sha, false)
+		if err != nil {
+			return "", fmt.Errorf("failed to get tree: %w", classifyError(err))
+		}
+
+		found := false
+		for _, e := range tree.Entries {
+			if e.GetPath() != name {
+				continue
+			}
+
+			if e.GetType() != "tree" {
+				return "", fmt.Errorf("%s is not a directory", p)
+			}
+
+			sha = e.GetSHA()
+			found = true
+			break
+		}
+
+		if !found {
+			return "", fmt.Errorf("directory %s not found: %w", p, ErrNotFound)
+		}
+	}
+
+	return sha, nil
+}
+
+// writeBlob downloads the blob for the tree entry and writes it to the directory, symbolic links
+// are stored as blobs containing the target of the link
+func writeBlob(ctx context.Context, client *github.Client, owner, repo string, e *github.TreeEntry, dir string) error {
+	data, _, err := client.Git.GetBlobRaw(ctx, owner, repo, e.GetSHA())
+	if err != nil {
+		return fmt.Errorf("failed to get blob for %s: %w", e.GetPath(), classifyError(err))
+	}
+
+	dest := filepath.Join(dir, filepath.FromSlash(e.GetPath()))
+	if !strings.HasPrefix(dest, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return fmt.Errorf("invalid path %s in tree", e.GetPath())
+	}
+
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	switch e.GetMode() {
+	case "120000":
+		err = os.Symlink(string(data), dest)
+	case "100755":
+		err = os.WriteFile(dest, data, 0755)
+	default:
+		err = os.WriteFile(dest, data, 0644)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", e.GetPath(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// ConventionalVersion is the next version calculated from conventional commits
+type ConventionalVersion struct {
+	// Version is the next version, empty when none of the commits require a release
+	Version string
+	// Commits are the commits that caused the version to be incremented
+	Commits []*ConventionalCommit
+}
+
+// ConventionalCommit is a commit with a conventional commit message
+type ConventionalCommit struct {
+	SHA         string
+	Type        string
+	Scope       string
+	Description string
+	Breaking    bool
+	// Bump is the version increment for the commit, `major`, `minor` or `patch`
+	Bump string
+}
+
+// NextVersionFromConventionalCommits returns the next semantic version based on the conventional commit
+// messages for the commits between the latest semantic version tag and the given commit SHA.
+//
+// i.e. if the current tag is `1.1.2` and one of the commits is a breaking change, `feat!: ...` or a commit with
+// a `BREAKING CHANGE:` footer, the next version will be `2.0.0`, if the commits contain a `feat: ...` the next
+// version will be `1.2.0` and if the commits contain a `fix: ...` the next version will be `1.1.3`.
+// When tagPrefix is set only tags starting with the prefix are considered and the version includes the prefix.
+func (m *Github) NextVersionFromConventionalCommits(
+	ctx context.Context,
+	owner,
+	repo,
+	sha string,
+	// +optional
+	tagPrefix string,
+) (*ConventionalVersion, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := listTags(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := parseVersions(tagNames(tags), tagPrefix)
+	tag, cv := latestTag(versions, tagPrefix)
+
+	commits, err := listCommitsSince(ctx, client, owner, repo, tag, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, bump := conventionalBump(commits)
+
+	log.Debug("Setting version increment", "bump", bump, "tag", tag, "commits", len(commits))
+
+	v, err := incrementVersion(cv, versions, bump, "", tagPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConventionalVersion{Version: v, Commits: cc}, nil
+}
+
+var conventionalHeader = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: (.+)$`)
+
+// parseConventionalCommit parses a commit message, returns false if the message is not a conventional commit
+func parseConventionalCommit(sha, message string) (*ConventionalCommit, bool) {
+	header, body, _ := strings.Cut(message, "\n")
+
+	parts := conventionalHeader.FindStringSubmatch(strings.TrimSpace(header))
+	if parts == nil {
+		return nil, false
+	}
+
+	c := &ConventionalCommit{
+		SHA:         sha,
+		Type:        strings.ToLower(parts[1]),
+		Scope:       parts[2],
+		Description: parts[4],
+		Breaking:    parts[3] == "!",
+	}
+
+	for _, l := range strings.Split(body, "\n") {
+		if strings.HasPrefix(l, "BREAKING CHANGE:") || strings.HasPrefix(l, "BREAKING-CHANGE:") {
+			c.Breaking = true
+		}
+	}
+
+	switch {
+	case c.Breaking:
+		c.Bump = "major"
+	case c.Type == "feat":
+		c.Bump = "minor"
+	case c.Type == "fix":
+		c.Bump = "patch"
+	}
+
+	return c, true
+}
+
+// conventionalBump returns the commits that increment the version and the highest increment
+func conventionalBump(commits []*github.RepositoryCommit) ([]*ConventionalCommit, string) {
+	cc := []*ConventionalCommit{}
+	bump := ""
+
+	for _, rc := range commits {
+		c, ok := parseConventionalCommit(rc.GetSHA(), rc.GetCommit().GetMessage())
+		if !ok || c.Bump == "" {
+			continue
+		}
+
+		cc = append(cc, c)
+
+		if bumpRank(c.Bump) > bumpRank(bump) {
+			bump = c.Bump
+		}
+	}
+
+	return cc, bump
+}
+
+// bumpRank orders the version increments so that the highest can be selected
+func bumpRank(bump string) int {
+	switch bump {
+	case "major":
+		return 3
+	case "minor":
+		return 2
+	case "patch":
+		return 1
+	}
+
+	return 0
+}
+
+// listCommitsSince returns the commits reachable from sha that are not reachable from base, when base is
+// empty all the commits reachable from sha are returned
+func listCommitsSince(ctx context.Context, client *github.Client, owner, repo, base, sha string) ([]*github.RepositoryCommit, error) {
+	commits := []*github.RepositoryCommit{}
+	page := 0
+
+	for {
+		var c []*github.RepositoryCommit
+		var resp *github.Response
+		var err error
+
+		if base == "" {
+			c, resp, err = client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+				SHA:         sha,
+				ListOptions: github.ListOptions{Page: page, PerPage: 100},
+			})
+		} else {
+			var cmp *github.CommitsComparison
+			cmp, resp, err = client.Repositories.CompareCommits(ctx, owner, repo, base, sha, &github.ListOptions{Page: page, PerPage: 100})
+			if cmp != nil {
+				c = cmp.Commits
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to list commits: %w", classifyError(err))
+		}
+
+		commits = append(commits, c...)
+
+		page = resp.NextPage
+		if page == 0 {
+			break
+		}
+	}
+
+	return commits, nil
+}
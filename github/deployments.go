@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// CreateDeployment creates a deployment of the ref to the given environment and returns the id of the deployment.
+// The payload is a JSON object with extra information about the deployment. Required status checks are skipped
+// unless requiredContexts is set, and the default branch is only merged into the ref when autoMerge is set. When
+// the merge creates a new commit GitHub does not create the deployment and an error is returned.
+// The deployment does not have a status until one is set with SetDeploymentStatus.
+func (m *Github) CreateDeployment(
+	ctx context.Context,
+	owner,
+	repo,
+	ref,
+	environment string,
+	// +optional
+	description string,
+	// +optional
+	payload string,
+	// +optional
+	// +default="deploy"
+	task string,
+	// +optional
+	transientEnvironment bool,
+	// +optional
+	productionEnvironment bool,
+	// +optional
+	autoMerge bool,
+	// +optional
+	requiredContexts []string,
+) (int, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// an empty list of contexts must be sent to skip the checks
+	if requiredContexts == nil {
+		requiredContexts = []string{}
+	}
+
+	req := &github.DeploymentRequest{
+		Ref:                   &ref,
+		Task:                  &task,
+		Environment:           &environment,
+		AutoMerge:             &autoMerge,
+		RequiredContexts:      &requiredContexts,
+		TransientEnvironment:  &transientEnvironment,
+		ProductionEnvironment: &productionEnvironment,
+	}
+
+	if description != "" {
+		req.Description = &description
+	}
+
+	if payload != "" {
+		if !json.Valid([]byte(payload)) {
+			return 0, fmt.Errorf("payload is not valid JSON")
+		}
+
+		req.Payload = json.RawMessage(payload)
+	}
+
+	d, _, err := client.Repositories.CreateDeployment(ctx, owner, repo, req)
+
+	// GitHub responds with 202 and no deployment when the default branch was merged into the ref
+	var ae *github.AcceptedError
+	if errors.As(err, &ae) || (err == nil && d.GetID() == 0) {
+		return 0, fmt.Errorf("deployment was not created, GitHub merged the default branch into %s, deploy the merged commit or disable autoMerge", ref)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to create deployment: %w", classifyError(err))
+	}
+
+	log.Debug("Created deployment", "id", d.GetID(), "environment", environment, "sha", d.GetSHA())
+
+	return int(d.GetID()), nil
+}
+
+// SetDeploymentStatus sets the status of a deployment, state is one of error, failure, inactive, in_progress,
+// queued, pending or success. GitHub marks earlier deployments to non production environments inactive when a
+// deployment succeeds, use DeactivateDeployments for production environments.
+func (m *Github) SetDeploymentStatus(
+	ctx context.Context,
+	owner,
+	repo string,
+	deploymentID int,
+	state string,
+	// +optional
+	description string,
+	// +optional
+	environmentURL string,
+	// +optional
+	logURL string,
+	// +optional
+	environment string,
+) error {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &github.DeploymentStatusRequest{State: &state}
+
+	if description != "" {
+		req.Description = &description
+	}
+
+	if environmentURL != "" {
+		req.EnvironmentURL = &environmentURL
+	}
+
+	if logURL != "" {
+		req.LogURL = &logURL
+	}
+
+	if environment != "" {
+		req.Environment = &environment
+	}
+
+	_, _, err = client.Repositories.CreateDeploymentStatus(ctx, owner, repo, int64(deploymentID), req)
+	if err != nil {
+		return fmt.Errorf("failed to set deployment status: %w", classifyError(err))
+	}
+
+	log.Debug("Set deployment status", "id", deploymentID, "state", state)
+
+	return nil
+}
+
+// DeactivateDeployments marks the active deployments to the environment inactive and returns the number of
+// deployments that were changed. A deployment is active when its latest status is success. When
+// keepDeploymentID is set only deployments created before it are deactivated, so a newer deployment from
+// another pipeline stays active.
+func (m *Github) DeactivateDeployments(
+	ctx context.Context,
+	owner,
+	repo,
+	environment string,
+	// +optional
+	keepDeploymentID int,
+) (int, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deployments, err := listDeployments(ctx, client, owner, repo, environment)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, d := range deployments {
+		// deployment ids increase so deployments with a higher id were created after the kept deployment
+		if keepDeploymentID > 0 && d.GetID() >= int64(keepDeploymentID) {
+			continue
+		}
+
+		// statuses are returned newest first
+		statuses, _, err := client.Repositories.ListDeploymentStatuses(ctx, owner, repo, d.GetID(), &github.ListOptions{PerPage: 1})
+		if err != nil {
+			return 0, fmt.Errorf("failed to list deployment statuses: %w", classifyError(err))
+		}
+
+		if len(statuses) == 0 || statuses[0].GetState() != "success" {
+			continue
+		}
+
+		_, _, err = client.Repositories.CreateDeploymentStatus(ctx, owner, repo, d.GetID(), &github.DeploymentStatusRequest{
+			State: github.String("inactive"),
+		})
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to deactivate deployment %d: %w", d.GetID(), classifyError(err))
+		}
+
+		log.Debug("Deactivated deployment", "id", d.GetID(), "environment", environment)
+		count++
+	}
+
+	return count, nil
+}
+
+// listDeployments returns all the deployments to the environment
+func listDeployments(ctx context.Context, client *github.Client, owner, repo, environment string) ([]*github.Deployment, error) {
+	deployments := []*github.Deployment{}
+	page := 0
+
+	for {
+		ds, resp, err := client.Repositories.ListDeployments(ctx, owner, repo, &github.DeploymentsListOptions{
+			Environment: environment,
+			ListOptions: github.ListOptions{Page: page, PerPage: 100},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", classifyError(err))
+		}
+
+		deployments = append(deployments, ds...)
+
+		if resp.NextPage == 0 {
+			return deployments, nil
+		}
+
+		page = resp.NextPage
+	}
+}
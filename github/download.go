@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"main/internal/dagger"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// DownloadReleaseAssets downloads the assets of a release that match the glob pattern, i.e. `*_linux_*.tar.gz`,
+// and returns them as a directory. The tag `latest` downloads the assets from the latest release.
+// If the release has a checksum file, i.e. `SHA256SUMS` or `checksums.txt`, every downloaded asset is verified
+// and an error is returned if the checksum does not match or the asset is not listed in the file. The checksum
+// file, signatures, certificates, SBOMs and provenance such as `provenance.intoto.jsonl` are only verified when
+// they are listed in the checksums.
+func (m *Github) DownloadReleaseAssets(
+	ctx context.Context,
+	owner,
+	repo,
+	tag string,
+	// +optional
+	// +default="*"
+	pattern string,
+) (*dagger.Directory, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rel *github.RepositoryRelease
+	if tag == "latest" {
+		rel, _, err = client.Repositories.GetLatestRelease(ctx, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest release: %w", classifyError(err))
+		}
+	} else {
+		rel, err = findRelease(ctx, client, owner, repo, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if rel == nil {
+			return nil, fmt.Errorf("release for tag %s not found", tag)
+		}
+	}
+
+	assets, err := listReleaseAssets(ctx, client, owner, repo, rel.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch the checksums first so that each asset can be verified as it is downloaded
+	sums := map[string]string{}
+	for name, a := range assets {
+		if !isChecksumFile(name) {
+			continue
+		}
+
+		s, err := downloadChecksums(ctx, client, owner, repo, a)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range s {
+			sums[k] = v
+		}
+	}
+
+	// assets are downloaded to the module working directory so they can be loaded as a directory
+	dir, err := os.MkdirTemp(".", "release-assets-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, a := range assets {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		sum, err := downloadReleaseAsset(ctx, client, owner, repo, a, filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sums) == 0 || isChecksumFile(name) {
+			log.Debug("Downloaded release asset", "name", name, "verified", false)
+			continue
+		}
+
+		expected, ok := sums[name]
+		if !ok && isSidecar(name) {
+			log.Debug("Downloaded release asset", "name", name, "verified", false)
+			continue
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("asset %s is not listed in the release checksums", name)
+		}
+
+		if expected != sum {
+			return nil, fmt.Errorf("checksum mismatch for %s, expected %s, got %s", name, expected, sum)
+		}
+
+		log.Debug("Downloaded release asset", "name", name, "verified", true)
+	}
+
+	return dag.CurrentModule().Workdir(dir).Sync(ctx)
+}
+
+// isChecksumFile returns true if the asset name is a known checksum file format, i.e. `SHA256SUMS`,
+// `app_1.0.0_SHA256SUMS.txt` or `checksums.txt`
+func isChecksumFile(name string) bool {
+	n := strings.ToLower(name)
+
+	for _, suffix := range []string{"sha256sums", "sha256sums.txt", "checksums.txt"} {
+		if strings.HasSuffix(n, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSidecar returns true if the asset describes another asset, i.e. a signature, certificate, SBOM or
+// provenance, these are usually created after the checksums so are not listed in the checksum file
+func isSidecar(name string) bool {
+	n := strings.ToLower(name)
+
+	for _, ext := range []string{".sig", ".minisig", ".asc", ".pem", ".bundle", ".sbom", ".sbom.json", ".spdx.json", ".cdx.json", ".intoto.jsonl"} {
+		if strings.HasSuffix(n, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// downloadReleaseAsset downloads an asset to the given file and returns the hex encoded sha256 checksum,
+// assets are downloaded through the API so that assets on private repositories can be downloaded
+func downloadReleaseAsset(ctx context.Context, client *github.Client, owner, repo string, a *github.ReleaseAsset, dest string) (string, error) {
+	rc, _, err := client.Repositories.DownloadReleaseAsset(ctx, owner, repo, a.GetID(), http.DefaultClient)
+	if err != nil {
+		return "", fmt.Errorf("failed to download asset %s: %w", a.GetName(), classifyError(err))
+	}
+	defer rc.Close()
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), rc)
+	if err != nil {
+		return "", fmt.Errorf("failed to download asset %s: %w", a.GetName(), err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// downloadChecksums downloads a checksum file in the format written by sha256sum and returns the
+// checksums keyed by file name
+func downloadChecksums(ctx context.Context, client *github.Client, owner, repo string, a *github.ReleaseAsset) (map[string]string, error) {
+	rc, _, err := client.Repositories.DownloadReleaseAsset(ctx, owner, repo, a.GetID(), http.DefaultClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download checksums %s: %w", a.GetName(), classifyError(err))
+	}
+	defer rc.Close()
+
+	return parseChecksums(rc)
+}
+
+// parseChecksums parses lines in the form `<sha256>  <name>`, binary mode names prefixed with `*` are supported
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		sums[path.Base(strings.TrimPrefix(fields[1], "*"))] = strings.ToLower(fields[0])
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	return sums, nil
+}
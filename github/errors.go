@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	// ErrNotFound is returned when the GitHub API responds that a resource does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is returned when the GitHub API rejects a change because the resource has changed
+	// or already exists, for example a fast forward of a branch that has new commits
+	ErrConflict = errors.New("conflict")
+
+	// ErrRateLimited is returned when a request is still rate limited after it has been retried
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// APIError is a failed request to the GitHub API, it matches one of ErrNotFound, ErrConflict
+// or ErrRateLimited with errors.Is and the original go-github error with errors.As
+type APIError struct {
+	// Kind is the sentinel error that describes the failure
+	Kind error
+
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Err is the error returned by the go-github client
+	Err error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
+
+func (e *APIError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError wraps errors returned by the GitHub API in an APIError when they are a not found,
+// conflict or rate limit failure, any other error is returned unchanged
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ae *APIError
+	if errors.As(err, &ae) {
+		return err
+	}
+
+	var rle *github.RateLimitError
+	if errors.As(err, &rle) {
+		return &APIError{Kind: ErrRateLimited, StatusCode: statusCode(rle.Response), Err: err}
+	}
+
+	var are *github.AbuseRateLimitError
+	if errors.As(err, &are) {
+		return &APIError{Kind: ErrRateLimited, StatusCode: statusCode(are.Response), Err: err}
+	}
+
+	var ge *github.ErrorResponse
+	if !errors.As(err, &ge) {
+		return err
+	}
+
+	switch statusCode(ge.Response) {
+	case http.StatusNotFound:
+		return &APIError{Kind: ErrNotFound, StatusCode: http.StatusNotFound, Err: err}
+	case http.StatusConflict:
+		return &APIError{Kind: ErrConflict, StatusCode: http.StatusConflict, Err: err}
+	case http.StatusUnprocessableEntity:
+		// creating a ref or a release that already exists is a validation failure rather than a 409
+		for _, e := range ge.Errors {
+			if e.Code == "already_exists" {
+				return &APIError{Kind: ErrConflict, StatusCode: http.StatusUnprocessableEntity, Err: err}
+			}
+		}
+
+		// deleting a ref that does not exist is also a validation failure rather than a 404
+		if ge.Message == "Reference does not exist" {
+			return &APIError{Kind: ErrNotFound, StatusCode: http.StatusUnprocessableEntity, Err: err}
+		}
+	case http.StatusTooManyRequests:
+		return &APIError{Kind: ErrRateLimited, StatusCode: http.StatusTooManyRequests, Err: err}
+	}
+
+	return err
+}
+
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+
+	return resp.StatusCode
+}
+
+// isNotFound returns true when the error is a 404 response from the GitHub API
+func isNotFound(err error) bool {
+	return errors.Is(classifyError(err), ErrNotFound)
+}
+
+// isConflict returns true when the GitHub API rejected a change because of the current state of the resource
+func isConflict(err error) bool {
+	return errors.Is(classifyError(err), ErrConflict)
+}
+
+// isRateLimited returns true when the request was rate limited by the GitHub API
+func isRateLimited(err error) bool {
+	return errors.Is(classifyError(err), ErrRateLimited)
+}
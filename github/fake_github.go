@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+)
+
+// fakeGitHub is a minimal in process implementation of the GitHub REST API, it allows the
+// FTest functions to run the module without access to a real repository
+type fakeGitHub struct {
+	mux *http.ServeMux
+}
+
+// newFakeGitHub creates a fake GitHub API with no routes
+func newFakeGitHub() *fakeGitHub {
+	return &fakeGitHub{mux: http.NewServeMux()}
+}
+
+// handle registers a handler for the given pattern, i.e. `GET /repos/{owner}/{repo}/tags`
+func (f *fakeGitHub) handle(pattern string, h http.HandlerFunc) {
+	f.mux.HandleFunc(pattern, h)
+}
+
+// start starts the fake API server, the server must be closed by the caller
+func (f *fakeGitHub) start() *httptest.Server {
+	return httptest.NewServer(f.mux)
+}
+
+// client returns a module that uses the fake API server
+func (f *fakeGitHub) client(srv *httptest.Server) *Github {
+	return &Github{
+		Token:   dag.SetSecret("fake-github-token", "fake"),
+		BaseURL: srv.URL,
+	}
+}
+
+// writePage writes the requested page of items as JSON, a Link header is added when there are more pages
+func writePage[T any](w http.ResponseWriter, r *http.Request, pages [][]T) {
+	page := requestedPage(w, r, len(pages))
+
+	items := []T{}
+	if page <= len(pages) {
+		items = pages[page-1]
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// writePageObject writes the first object of the requested page, this is used for APIs
+// like compare that return an object containing a page of items
+func writePageObject[T any](w http.ResponseWriter, r *http.Request, pages [][]T) {
+	page := requestedPage(w, r, len(pages))
+
+	if page > len(pages) || len(pages[page-1]) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pages[page-1][0])
+}
+
+// requestedPage returns the page number for the request and adds a Link header for the next page
+// when there are more pages
+func requestedPage(w http.ResponseWriter, r *http.Request, pages int) int {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	if page < pages {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+	}
+
+	return page
+}
+
+// writeJSON writes the value as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,2415 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"main/internal/dagger"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// nextVersionTest is a test case for NextVersionFromAssociatedPRLabel run against the fake API
+type nextVersionTest struct {
+	name     string
+	prs      [][]*github.PullRequest
+	tags     [][]string
+	cfg      versionConfig
+	expected string
+}
+
+// example: dagger call ftest-next-version-from-associated-prlabel
+func (m *Github) FTestNextVersionFromAssociatedPRLabel(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	defaults := versionConfig{MajorLabel: "major", MinorLabel: "minor", PatchLabel: "patch"}
+
+	tests := []nextVersionTest{
+		{
+			name: "uses the label from the latest of multiple PRs",
+			prs: [][]*github.PullRequest{{
+				testPR(1, "major"),
+				testPR(5, "patch"),
+				testPR(3, "minor"),
+			}},
+			tags:     [][]string{{"v1.2.3", "v1.2.2"}},
+			cfg:      defaults,
+			expected: "1.2.4",
+		},
+		{
+			name:     "uses the highest label on a PR",
+			prs:      [][]*github.PullRequest{{testPR(1, "patch", "major", "minor")}},
+			tags:     [][]string{{"v1.2.3"}},
+			cfg:      defaults,
+			expected: "2.0.0",
+		},
+		{
+			name: "reads all pages of PRs and tags",
+			prs: [][]*github.PullRequest{
+				{testPR(1, "patch"), testPR(2, "patch")},
+				{testPR(7, "minor")},
+			},
+			tags: [][]string{
+				{"v1.0.0", "v1.1.0"},
+				{"v2.0.0"},
+				{"v1.5.0"},
+			},
+			cfg:      defaults,
+			expected: "2.1.0",
+		},
+		{
+			name:     "ignores tags that are not semantic versions",
+			prs:      [][]*github.PullRequest{{testPR(1, "minor")}},
+			tags:     [][]string{{"latest", "nightly", "vault/v9.0.0", "v1.0.0"}},
+			cfg:      defaults,
+			expected: "1.1.0",
+		},
+		{
+			name:     "starts from 0.0.0 when there are no tags",
+			prs:      [][]*github.PullRequest{{testPR(1, "minor")}},
+			tags:     [][]string{},
+			cfg:      defaults,
+			expected: "0.1.0",
+		},
+		{
+			name:     "returns no version when the PR has no labels",
+			prs:      [][]*github.PullRequest{{testPR(1, "documentation")}},
+			tags:     [][]string{{"v1.0.0"}},
+			cfg:      defaults,
+			expected: "",
+		},
+		{
+			name:     "returns no version when there are no PRs",
+			prs:      [][]*github.PullRequest{},
+			tags:     [][]string{{"v1.0.0"}},
+			cfg:      defaults,
+			expected: "",
+		},
+		{
+			name: "uses the default bump when the PR has no labels",
+			prs:  [][]*github.PullRequest{{testPR(1)}},
+			tags: [][]string{{"v1.0.0"}},
+			cfg: versionConfig{
+				MajorLabel:  "major",
+				MinorLabel:  "minor",
+				PatchLabel:  "patch",
+				DefaultBump: "patch",
+			},
+			expected: "1.0.1",
+		},
+		{
+			name: "uses custom labels",
+			prs:  [][]*github.PullRequest{{testPR(1, "minor", "semver:major")}},
+			tags: [][]string{{"v1.0.0"}},
+			cfg: versionConfig{
+				MajorLabel: "semver:major",
+				MinorLabel: "semver:minor",
+				PatchLabel: "semver:patch",
+			},
+			expected: "2.0.0",
+		},
+		{
+			name: "only uses tags with the prefix",
+			prs:  [][]*github.PullRequest{{testPR(1, "patch")}},
+			tags: [][]string{{"v3.0.0", "vault/v1.0.0", "vault/v1.1.0", "brew/v2.0.0"}},
+			cfg: versionConfig{
+				MajorLabel: "major",
+				MinorLabel: "minor",
+				PatchLabel: "patch",
+				TagPrefix:  "vault/v",
+			},
+			expected: "vault/v1.1.1",
+		},
+		{
+			name: "increments the prerelease number",
+			prs:  [][]*github.PullRequest{{testPR(1, "minor", "semver:rc")}},
+			tags: [][]string{{"vault/v1.0.0", "vault/v1.1.0-rc.1", "vault/v1.1.0-beta.4"}},
+			cfg: versionConfig{
+				MajorLabel:       "major",
+				MinorLabel:       "minor",
+				PatchLabel:       "patch",
+				PrereleaseLabels: []string{"semver:rc", "semver:beta"},
+				TagPrefix:        "vault/v",
+			},
+			expected: "vault/v1.1.0-rc.2",
+		},
+	}
+
+	for _, tc := range tests {
+		v, err := runNextVersionTest(ctx, tc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tc.name, err)
+		}
+
+		if v != tc.expected {
+			return fmt.Errorf("%s: expected version %q, got %q", tc.name, tc.expected, v)
+		}
+
+		log.Info("PASS", "test", tc.name, "version", v)
+	}
+
+	return nil
+}
+
+func runNextVersionTest(ctx context.Context, tc nextVersionTest) (string, error) {
+	// fail rather than hang if the module does not stop paging
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tags := [][]*github.RepositoryTag{}
+	for _, page := range tc.tags {
+		p := []*github.RepositoryTag{}
+		for _, t := range page {
+			p = append(p, &github.RepositoryTag{Name: github.String(t)})
+		}
+
+		tags = append(tags, p)
+	}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/commits/{sha}/pulls", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, r, tc.prs)
+	})
+	f.handle("GET /repos/{owner}/{repo}/tags", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, r, tags)
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	return f.client(srv).NextVersionFromAssociatedPRLabel(
+		ctx,
+		"jumppad-labs",
+		"daggerverse",
+		"6976eb3f392256c384e87094853853f90c64ca68",
+		tc.cfg.MajorLabel,
+		tc.cfg.MinorLabel,
+		tc.cfg.PatchLabel,
+		tc.cfg.PrereleaseLabels,
+		tc.cfg.DefaultBump,
+		tc.cfg.TagPrefix,
+	)
+}
+
+// testPR returns a pull request with the given number and labels
+func testPR(number int, labels ...string) *github.PullRequest {
+	pr := &github.PullRequest{Number: github.Int(number)}
+	for _, l := range labels {
+		pr.Labels = append(pr.Labels, &github.Label{Name: github.String(l)})
+	}
+
+	return pr
+}
+
+// example: dagger call ftest-version-calculation
+func (m *Github) FTestVersionCalculation(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	parseTests := []struct {
+		name     string
+		tags     []string
+		prefix   string
+		expected []string
+	}{
+		{
+			name:     "sorts versions",
+			tags:     []string{"v1.0.0", "v0.9.0", "v1.0.0-rc.1", "v1.10.0", "v1.2.0"},
+			expected: []string{"0.9.0", "1.0.0-rc.1", "1.0.0", "1.2.0", "1.10.0"},
+		},
+		{
+			name:     "ignores tags that are not versions",
+			tags:     []string{"latest", "v1.0.0", "nightly-20240101"},
+			expected: []string{"1.0.0"},
+		},
+		{
+			name:     "only includes tags with the prefix",
+			tags:     []string{"v5.0.0", "vault/v1.0.0", "vault/v1.1.0", "deb/v2.0.0"},
+			prefix:   "vault/v",
+			expected: []string{"1.0.0", "1.1.0"},
+		},
+		{
+			name:     "tags with a prefix are not versions without the prefix",
+			tags:     []string{"v5.0.0", "vault/v1.0.0"},
+			expected: []string{"5.0.0"},
+		},
+	}
+
+	for _, tc := range parseTests {
+		got := []string{}
+		for _, v := range parseVersions(tc.tags, tc.prefix) {
+			got = append(got, v.String())
+		}
+
+		if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+			return fmt.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+
+		log.Info("PASS", "test", tc.name, "versions", got)
+	}
+
+	incrementTests := []struct {
+		name     string
+		current  string
+		tags     []string
+		bump     string
+		channel  string
+		prefix   string
+		expected string
+		err      bool
+	}{
+		{name: "major", current: "1.2.3", bump: "major", expected: "2.0.0"},
+		{name: "minor", current: "1.2.3", bump: "minor", expected: "1.3.0"},
+		{name: "patch", current: "1.2.3", bump: "patch", expected: "1.2.4"},
+		{name: "no increment", current: "1.2.3", expected: ""},
+		{name: "invalid increment", current: "1.2.3", bump: "huge", err: true},
+		{name: "prefix", current: "1.2.3", bump: "minor", prefix: "vault/v", expected: "vault/v1.3.0"},
+		{name: "first prerelease", current: "1.2.3", bump: "minor", channel: "rc", expected: "1.3.0-rc.1"},
+		{
+			name:     "prerelease on top of an existing prerelease",
+			current:  "1.2.3",
+			tags:     []string{"v1.2.3", "v1.3.0-rc.1", "v1.3.0-rc.2"},
+			bump:     "minor",
+			channel:  "rc",
+			expected: "1.3.0-rc.3",
+		},
+		{
+			name:     "prerelease numbers are compared as numbers",
+			current:  "1.2.3",
+			tags:     []string{"v1.3.0-rc.9", "v1.3.0-rc.10"},
+			bump:     "minor",
+			channel:  "rc",
+			expected: "1.3.0-rc.11",
+		},
+		{
+			name:     "changing channel starts a new sequence",
+			current:  "1.2.3",
+			tags:     []string{"v1.3.0-rc.1", "v1.3.0-rc.2"},
+			bump:     "minor",
+			channel:  "beta",
+			expected: "1.3.0-beta.1",
+		},
+		{
+			name:     "prereleases of other versions are ignored",
+			current:  "1.2.3",
+			tags:     []string{"v1.2.4-rc.4", "v2.0.0-rc.2"},
+			bump:     "minor",
+			channel:  "rc",
+			expected: "1.3.0-rc.1",
+		},
+		{
+			name:     "prerelease with prefix",
+			current:  "1.0.0",
+			tags:     []string{"vault/v1.0.1-rc.1"},
+			bump:     "patch",
+			channel:  "rc",
+			prefix:   "vault/v",
+			expected: "vault/v1.0.1-rc.2",
+		},
+		{name: "invalid channel", current: "1.2.3", bump: "patch", channel: "rc_1", err: true},
+	}
+
+	for _, tc := range incrementTests {
+		got, err := incrementVersion(semver.MustParse(tc.current), parseVersions(tc.tags, tc.prefix), tc.bump, tc.channel, tc.prefix)
+		if tc.err {
+			if err == nil {
+				return fmt.Errorf("%s: expected an error, got %q", tc.name, got)
+			}
+
+			log.Info("PASS", "test", tc.name, "error", err)
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", tc.name, err)
+		}
+
+		if got != tc.expected {
+			return fmt.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+
+		log.Info("PASS", "test", tc.name, "version", got)
+	}
+
+	cfg := versionConfig{
+		MajorLabel:       "major",
+		MinorLabel:       "minor",
+		PatchLabel:       "patch",
+		PrereleaseLabels: []string{"semver:rc", "semver:beta"},
+	}
+
+	nextTests := []struct {
+		name     string
+		prs      []*github.PullRequest
+		tags     []string
+		cfg      func(c versionConfig) versionConfig
+		expected string
+	}{
+		{
+			name:     "no tags",
+			prs:      []*github.PullRequest{testPR(1, "minor")},
+			expected: "0.1.0",
+		},
+		{
+			name:     "latest PR is used",
+			prs:      []*github.PullRequest{testPR(2, "patch"), testPR(3, "major"), testPR(1, "minor")},
+			tags:     []string{"v1.2.3"},
+			expected: "2.0.0",
+		},
+		{
+			name:     "highest label on the PR is used",
+			prs:      []*github.PullRequest{testPR(1, "patch", "minor")},
+			tags:     []string{"v1.2.3"},
+			expected: "1.3.0",
+		},
+		{
+			name:     "prereleases are not the current version",
+			prs:      []*github.PullRequest{testPR(1, "patch")},
+			tags:     []string{"v1.2.3", "v1.3.0-rc.1"},
+			expected: "1.2.4",
+		},
+		{
+			name:     "prerelease label on top of an existing prerelease",
+			prs:      []*github.PullRequest{testPR(1, "minor", "semver:rc")},
+			tags:     []string{"v1.2.3", "v1.3.0-rc.1"},
+			expected: "1.3.0-rc.2",
+		},
+		{
+			name:     "changing channel",
+			prs:      []*github.PullRequest{testPR(1, "minor", "semver:beta")},
+			tags:     []string{"v1.2.3", "v1.3.0-rc.1"},
+			expected: "1.3.0-beta.1",
+		},
+		{
+			name:     "prerelease label without a version label is a patch",
+			prs:      []*github.PullRequest{testPR(1, "semver:rc")},
+			tags:     []string{"v1.2.3"},
+			expected: "1.2.4-rc.1",
+		},
+		{
+			name:     "no labels",
+			prs:      []*github.PullRequest{testPR(1, "documentation")},
+			tags:     []string{"v1.2.3"},
+			expected: "",
+		},
+		{
+			name:     "default bump",
+			prs:      []*github.PullRequest{testPR(1, "documentation")},
+			tags:     []string{"v1.2.3"},
+			cfg:      func(c versionConfig) versionConfig { c.DefaultBump = "minor"; return c },
+			expected: "1.3.0",
+		},
+		{
+			name:     "labels override the default bump",
+			prs:      []*github.PullRequest{testPR(1, "patch")},
+			tags:     []string{"v1.2.3"},
+			cfg:      func(c versionConfig) versionConfig { c.DefaultBump = "minor"; return c },
+			expected: "1.2.4",
+		},
+		{
+			name:     "tag prefix",
+			prs:      []*github.PullRequest{testPR(1, "minor")},
+			tags:     []string{"v5.0.0", "vault/v1.0.0"},
+			cfg:      func(c versionConfig) versionConfig { c.TagPrefix = "vault/v"; return c },
+			expected: "vault/v1.1.0",
+		},
+		{
+			name:     "tag without a prefix is not used for a prefixed version",
+			prs:      []*github.PullRequest{testPR(1, "minor")},
+			tags:     []string{"v5.0.0"},
+			cfg:      func(c versionConfig) versionConfig { c.TagPrefix = "vault/v"; return c },
+			expected: "vault/v0.1.0",
+		},
+	}
+
+	for _, tc := range nextTests {
+		c := cfg
+		if tc.cfg != nil {
+			c = tc.cfg(cfg)
+		}
+
+		got, err := nextVersion(tc.prs, tc.tags, c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tc.name, err)
+		}
+
+		if got != tc.expected {
+			return fmt.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+
+		log.Info("PASS", "test", tc.name, "version", got)
+	}
+
+	return nil
+}
+
+// conventionalTest is a test case for NextVersionFromConventionalCommits run against the fake API
+type conventionalTest struct {
+	name    string
+	tags    []string
+	commits [][]string
+	prefix  string
+	// expectedBase is the tag the commits should be compared with, empty when all commits are listed
+	expectedBase    string
+	expected        string
+	expectedCommits int
+}
+
+// example: dagger call ftest-next-version-from-conventional-commits
+func (m *Github) FTestNextVersionFromConventionalCommits(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	tests := []conventionalTest{
+		{
+			name:            "feat increments the minor version",
+			tags:            []string{"v1.0.0", "v1.1.0", "nightly"},
+			commits:         [][]string{{"fix: broken link", "feat(github): add changelog", "docs: update readme"}},
+			expectedBase:    "v1.1.0",
+			expected:        "1.2.0",
+			expectedCommits: 2,
+		},
+		{
+			name:            "fix increments the patch version",
+			tags:            []string{"v1.1.0", "v1.2.0-rc.1"},
+			commits:         [][]string{{"fix(vault): handle missing token", "chore: tidy"}},
+			expectedBase:    "v1.1.0",
+			expected:        "1.1.1",
+			expectedCommits: 1,
+		},
+		{
+			name:            "exclamation mark is a breaking change",
+			tags:            []string{"v1.1.0"},
+			commits:         [][]string{{"feat: new api", "refactor!: remove old api"}},
+			expectedBase:    "v1.1.0",
+			expected:        "2.0.0",
+			expectedCommits: 2,
+		},
+		{
+			name:            "breaking change footer is a breaking change",
+			tags:            []string{"v1.1.0"},
+			commits:         [][]string{{"fix: rename flag\n\nBREAKING CHANGE: the flag --foo is now --bar"}},
+			expectedBase:    "v1.1.0",
+			expected:        "2.0.0",
+			expectedCommits: 1,
+		},
+		{
+			name:            "reads all pages of commits",
+			tags:            []string{"v1.1.0"},
+			commits:         [][]string{{"fix: one", "chore: two"}, {"feat: three"}},
+			expectedBase:    "v1.1.0",
+			expected:        "1.2.0",
+			expectedCommits: 2,
+		},
+		{
+			name:            "returns no version when there are no releasable commits",
+			tags:            []string{"v1.1.0"},
+			commits:         [][]string{{"docs: update readme", "not a conventional commit"}},
+			expectedBase:    "v1.1.0",
+			expected:        "",
+			expectedCommits: 0,
+		},
+		{
+			name:            "lists all commits when there are no tags",
+			tags:            []string{},
+			commits:         [][]string{{"feat: initial release"}},
+			expected:        "0.1.0",
+			expectedCommits: 1,
+		},
+		{
+			name:            "only uses tags with the prefix",
+			tags:            []string{"v3.0.0", "deb/v0.2.0", "vault/v0.1.0"},
+			commits:         [][]string{{"feat: add packages"}},
+			prefix:          "deb/v",
+			expectedBase:    "deb/v0.2.0",
+			expected:        "deb/v0.3.0",
+			expectedCommits: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		v, err := runConventionalTest(ctx, tc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tc.name, err)
+		}
+
+		if v.Version != tc.expected {
+			return fmt.Errorf("%s: expected version %q, got %q", tc.name, tc.expected, v.Version)
+		}
+
+		if len(v.Commits) != tc.expectedCommits {
+			return fmt.Errorf("%s: expected %d commits, got %d", tc.name, tc.expectedCommits, len(v.Commits))
+		}
+
+		log.Info("PASS", "test", tc.name, "version", v.Version)
+	}
+
+	return nil
+}
+
+func runConventionalTest(ctx context.Context, tc conventionalTest) (*ConventionalVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sha := "6976eb3f392256c384e87094853853f90c64ca68"
+
+	tags := []*github.RepositoryTag{}
+	for _, t := range tc.tags {
+		tags = append(tags, &github.RepositoryTag{Name: github.String(t)})
+	}
+
+	commits := [][]*github.RepositoryCommit{}
+	for i, page := range tc.commits {
+		p := []*github.RepositoryCommit{}
+		for j, msg := range page {
+			p = append(p, &github.RepositoryCommit{
+				SHA:    github.String(fmt.Sprintf("%d%d", i, j)),
+				Commit: &github.Commit{Message: github.String(msg)},
+			})
+		}
+
+		commits = append(commits, p)
+	}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/tags", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, r, [][]*github.RepositoryTag{tags})
+	})
+	f.handle("GET /repos/{owner}/{repo}/compare/{basehead}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("basehead") != tc.expectedBase+"..."+sha {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		cmp := [][]*github.CommitsComparison{}
+		for _, p := range commits {
+			cmp = append(cmp, []*github.CommitsComparison{{Commits: p}})
+		}
+
+		// the compare API returns a single object for each page rather than a list
+		writePageObject(w, r, cmp)
+	})
+	f.handle("GET /repos/{owner}/{repo}/commits", func(w http.ResponseWriter, r *http.Request) {
+		if tc.expectedBase != "" || r.URL.Query().Get("sha") != sha {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		writePage(w, r, commits)
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	return f.client(srv).NextVersionFromConventionalCommits(ctx, "jumppad-labs", "daggerverse", sha, tc.prefix)
+}
+
+// example: dagger call ftest-generate-changelog
+func (m *Github) FTestGenerateChangelog(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	merged := &github.Timestamp{Time: time.Now()}
+
+	prs := map[string][]*github.PullRequest{
+		"a1": {{Number: github.Int(10), Title: github.String("Add changelog"), MergedAt: merged, User: &github.User{Login: github.String("alice")}, Labels: []*github.Label{{Name: github.String("minor")}}}},
+		"b2": {
+			{Number: github.Int(11), Title: github.String("Fix paging"), MergedAt: merged, User: &github.User{Login: github.String("bob")}, Labels: []*github.Label{{Name: github.String("patch")}}},
+			{Number: github.Int(12), Title: github.String("Unmerged change"), User: &github.User{Login: github.String("bob")}},
+		},
+		"c3": {{Number: github.Int(10), Title: github.String("Add changelog"), MergedAt: merged, User: &github.User{Login: github.String("alice")}, Labels: []*github.Label{{Name: github.String("minor")}}}},
+		"d4": {{Number: github.Int(13), Title: github.String("Update CI"), MergedAt: merged, User: &github.User{Login: github.String("carol")}, Labels: []*github.Label{{Name: github.String("skip-changelog")}}}},
+		"e5": {{Number: github.Int(14), Title: github.String("Tidy docs"), MergedAt: merged, User: &github.User{Login: github.String("dave")}}},
+	}
+
+	commits := []*github.RepositoryCommit{}
+	for _, sha := range []string{"a1", "b2", "c3", "d4", "e5"} {
+		commits = append(commits, &github.RepositoryCommit{SHA: github.String(sha)})
+	}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/compare/{basehead}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.CommitsComparison{Commits: commits})
+	})
+	f.handle("GET /repos/{owner}/{repo}/commits/{sha}/pulls", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, prs[r.PathValue("sha")])
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	file, err := f.client(srv).GenerateChangelog(
+		ctx,
+		"jumppad-labs",
+		"daggerverse",
+		"v1.0.0",
+		"main",
+		"v1.1.0",
+		[]string{"major=Breaking Changes", "minor=Features", "patch=Bug Fixes"},
+		[]string{"skip-changelog"},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	md, err := file.Contents(ctx)
+	if err != nil {
+		return err
+	}
+
+	expected := `## v1.1.0
+
+### Features
+
+- Add changelog (#10) @alice
+
+### Bug Fixes
+
+- Fix paging (#11) @bob
+
+### Other Changes
+
+- Tidy docs (#14) @dave
+`
+
+	if md != expected {
+		return fmt.Errorf("unexpected changelog, expected:\n%s\ngot:\n%s", expected, md)
+	}
+
+	log.Info("PASS", "test", "generate changelog")
+
+	return nil
+}
+
+// example: dagger call ftest-next-module-versions
+func (m *Github) FTestNextModuleVersions(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sha := "6976eb3f392256c384e87094853853f90c64ca68"
+
+	commit := func(sha, msg string) *github.RepositoryCommit {
+		return &github.RepositoryCommit{
+			SHA: github.String(sha),
+			Commit: &github.Commit{
+				Message:   github.String(msg),
+				Committer: &github.CommitAuthor{Date: &github.Timestamp{Time: time.Now()}},
+			},
+		}
+	}
+
+	// c4 was merged from a long lived branch so it is older than the tags but is in the range
+	merged := commit("c4", "fix(deb): package name")
+	merged.Commit.Committer.Date = &github.Timestamp{Time: time.Now().AddDate(-1, 0, 0)}
+
+	tags := []*github.RepositoryTag{
+		{Name: github.String("vault/v1.0.0"), Commit: &github.Commit{SHA: github.String("b1")}},
+		{Name: github.String("deb/v0.1.0"), Commit: &github.Commit{SHA: github.String("b2")}},
+		{Name: github.String("v5.0.0"), Commit: &github.Commit{SHA: github.String("b3")}},
+	}
+
+	// commits changing each module, c0 is older than the latest vault tag
+	moduleCommits := map[string][]*github.RepositoryCommit{
+		"vault": {commit("c2", "feat(vault): add kv v2 support"), commit("c0", "feat(vault): initial module")},
+		"brew":  {commit("c3", "fix(brew): formula indentation")},
+		"deb":   {merged},
+	}
+
+	prs := map[string][]*github.PullRequest{
+		"c2": {testPR(5, "minor")},
+		"c3": {testPR(6, "patch")},
+		"c4": {testPR(7, "patch")},
+	}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/tags", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, tags)
+	})
+	f.handle("GET /repos/{owner}/{repo}/contents/", func(w http.ResponseWriter, r *http.Request) {
+		contents := []*github.RepositoryContent{}
+		for _, d := range []string{"vault", "deb", "brew", ".github"} {
+			contents = append(contents, &github.RepositoryContent{Name: github.String(d), Path: github.String(d), Type: github.String("dir")})
+		}
+
+		contents = append(contents, &github.RepositoryContent{Name: github.String("README.md"), Path: github.String("README.md"), Type: github.String("file")})
+		writeJSON(w, http.StatusOK, contents)
+	})
+	f.handle("GET /repos/{owner}/{repo}/commits/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, commit(r.PathValue("ref"), "release"))
+	})
+	f.handle("GET /repos/{owner}/{repo}/compare/{basehead}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.CommitsComparison{Commits: []*github.RepositoryCommit{
+			commit("c1", "chore: update readme"),
+			commit("c2", "feat(vault): add kv v2 support"),
+			commit("c3", "fix(brew): formula indentation"),
+			merged,
+		}})
+	})
+	f.handle("GET /repos/{owner}/{repo}/commits", func(w http.ResponseWriter, r *http.Request) {
+		since, _ := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+
+		cs := []*github.RepositoryCommit{}
+		for _, c := range moduleCommits[r.URL.Query().Get("path")] {
+			if !c.GetCommit().GetCommitter().GetDate().Before(since) {
+				cs = append(cs, c)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, cs)
+	})
+	f.handle("GET /repos/{owner}/{repo}/commits/{sha}/pulls", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, prs[r.PathValue("sha")])
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	expected := map[string]string{
+		"vault": "vault/v1.1.0",
+		"brew":  "brew/v0.0.1",
+		"deb":   "deb/v0.1.1",
+	}
+
+	for _, conventional := range []bool{false, true} {
+		mvs, err := f.client(srv).NextModuleVersions(ctx, "jumppad-labs", "daggerverse", sha, nil, "{module}/v", conventional, "major", "minor", "patch", nil, "")
+		if err != nil {
+			return err
+		}
+
+		if len(mvs) != len(expected) {
+			return fmt.Errorf("expected %d changed modules, got %d", len(expected), len(mvs))
+		}
+
+		for _, mv := range mvs {
+			if mv.Version != expected[mv.Module] {
+				return fmt.Errorf("expected version %q for %s, got %q", expected[mv.Module], mv.Module, mv.Version)
+			}
+
+			if len(mv.Commits) != 1 {
+				return fmt.Errorf("expected 1 commit for %s, got %d", mv.Module, len(mv.Commits))
+			}
+		}
+
+		log.Info("PASS", "test", "next module versions", "conventional", conventional)
+	}
+
+	return nil
+}
+
+// example: dagger call ftest-download-release-assets
+func (m *Github) FTestDownloadReleaseAssets(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	files := map[int64][]byte{
+		1: []byte("linux binary"),
+		2: []byte("darwin binary"),
+	}
+
+	sums := ""
+	for id, name := range map[int64]string{1: "app_linux_amd64", 2: "app_darwin_arm64"} {
+		sum := sha256.Sum256(files[id])
+		sums += fmt.Sprintf("%x  %s\n", sum, name)
+	}
+
+	files[3] = []byte(sums)
+
+	assets := []*github.ReleaseAsset{
+		{ID: github.Int64(1), Name: github.String("app_linux_amd64")},
+		{ID: github.Int64(2), Name: github.String("app_darwin_arm64")},
+		{ID: github.Int64(3), Name: github.String("SHA256SUMS")},
+		{ID: github.Int64(4), Name: github.String("SHA256SUMS.sig")},
+		{ID: github.Int64(5), Name: github.String("app_windows_amd64.exe")},
+		{ID: github.Int64(6), Name: github.String("app_linux_amd64.sig")},
+		{ID: github.Int64(7), Name: github.String("app_linux_amd64.sbom.json")},
+		{ID: github.Int64(8), Name: github.String("provenance.intoto.jsonl")},
+	}
+
+	// the signature must not be parsed as a checksum file
+	files[4] = []byte("MEUCIQDx signature")
+	files[5] = []byte("windows binary")
+
+	// signatures, SBOMs and provenance are created after the checksums so are not listed
+	files[6] = []byte("MEUCIQDy signature")
+	files[7] = []byte(`{"spdxVersion": "SPDX-2.3"}`)
+	files[8] = []byte(`{"payloadType": "application/vnd.in-toto+json"}`)
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.RepositoryRelease{ID: github.Int64(10), TagName: github.String("v1.0.0")})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.RepositoryRelease{ID: github.Int64(10), TagName: github.String(r.PathValue("tag"))})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/10/assets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, assets)
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/assets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(files[id])
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	dir, err := f.client(srv).DownloadReleaseAssets(ctx, "jumppad-labs", "jumppad", "latest", "*_linux_*")
+	if err != nil {
+		return err
+	}
+
+	entries, err := dir.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(entries, ",") != "app_linux_amd64,app_linux_amd64.sbom.json,app_linux_amd64.sig" {
+		return fmt.Errorf("expected only the linux assets to be downloaded, got %v", entries)
+	}
+
+	contents, err := dir.File("app_linux_amd64").Contents(ctx)
+	if err != nil {
+		return err
+	}
+
+	if contents != "linux binary" {
+		return fmt.Errorf("unexpected contents for app_linux_amd64: %q", contents)
+	}
+
+	// the checksum file and its signature are not listed in the checksums
+	dir, err = f.client(srv).DownloadReleaseAssets(ctx, "jumppad-labs", "jumppad", "latest", "SHA256SUMS*")
+	if err != nil {
+		return err
+	}
+
+	entries, err = dir.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) != 2 {
+		return fmt.Errorf("expected the checksums and signature to be downloaded, got %v", entries)
+	}
+
+	// provenance from CreateProvenance is uploaded to a release that already has checksums
+	dir, err = f.client(srv).DownloadReleaseAssets(ctx, "jumppad-labs", "jumppad", "latest", "*.intoto.jsonl")
+	if err != nil {
+		return err
+	}
+
+	entries, err = dir.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) != 1 || entries[0] != "provenance.intoto.jsonl" {
+		return fmt.Errorf("expected the provenance to be downloaded, got %v", entries)
+	}
+
+	// assets that are not in the checksums can not be verified
+	_, err = f.client(srv).DownloadReleaseAssets(ctx, "jumppad-labs", "jumppad", "latest", "*_windows_*")
+	if err == nil || !strings.Contains(err.Error(), "not listed") {
+		return fmt.Errorf("expected an error for an asset missing from the checksums, got %v", err)
+	}
+
+	log.Info("PASS", "test", "download release assets")
+
+	// corrupt the asset, the download should fail verification
+	files[1] = []byte("tampered binary")
+
+	_, err = f.client(srv).DownloadReleaseAssets(ctx, "jumppad-labs", "jumppad", "v1.0.0", "*_linux_*")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		return fmt.Errorf("expected checksum mismatch error, got %v", err)
+	}
+
+	log.Info("PASS", "test", "download release assets checksum mismatch")
+
+	return nil
+}
+
+// example: dagger call ftest-create-check-run
+func (m *Github) FTestCreateCheckRun(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	junit := &strings.Builder{}
+	junit.WriteString(`<?xml version="1.0" encoding="UTF-8"?><testsuites><testsuite name="github" file="github/version.go">`)
+	for i := 0; i < 120; i++ {
+		fmt.Fprintf(junit, `<testcase name="TestVersion%d" line="%d"><failure message="expected 1.2.0">stack trace</failure></testcase>`, i, i+1)
+	}
+	junit.WriteString(`<testcase name="TestPasses"/></testsuite>`)
+	// a class name is a package or class rather than a file so the failure can not be annotated
+	junit.WriteString(`<testsuite name="unknown"><testcase classname="github.com/jumppad-labs/daggerverse/github" name="TestNoFile"><failure message="no file"/></testcase></testsuite></testsuites>`)
+
+	cucumber := `[{"uri": "test/container.feature", "name": "Container", "elements": [{"name": "Run a container", "line": 3, "steps": [
+		{"keyword": "Given ", "name": "the environment is running", "line": 4, "result": {"status": "passed"}},
+		{"keyword": "Then ", "name": "the container is healthy", "line": 5, "result": {"status": "failed", "error_message": "timeout"}}
+	]}]}, {"name": "No URI", "elements": [{"name": "Missing file", "line": 1, "steps": [
+		{"keyword": "Then ", "name": "it fails", "line": 2, "result": {"status": "failed"}}
+	]}]}]`
+
+	reports := []*dagger.File{
+		dag.Directory().WithNewFile("junit.xml", junit.String()).File("junit.xml"),
+		dag.Directory().WithNewFile("cucumber.json", cucumber).File("cucumber.json"),
+	}
+
+	batches := []int{}
+	var conclusion string
+
+	f := newFakeGitHub()
+	f.handle("POST /repos/{owner}/{repo}/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		opts := github.CreateCheckRunOptions{}
+		json.NewDecoder(r.Body).Decode(&opts)
+
+		for _, a := range opts.Output.Annotations {
+			if a.GetPath() == "" {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "path is required"})
+				return
+			}
+		}
+
+		batches = append(batches, len(opts.Output.Annotations))
+		conclusion = opts.GetConclusion()
+
+		writeJSON(w, http.StatusCreated, &github.CheckRun{ID: github.Int64(42), Name: &opts.Name})
+	})
+	f.handle("PATCH /repos/{owner}/{repo}/check-runs/42", func(w http.ResponseWriter, r *http.Request) {
+		opts := github.UpdateCheckRunOptions{}
+		json.NewDecoder(r.Body).Decode(&opts)
+
+		if opts.Name != "tests" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "name is required"})
+			return
+		}
+
+		for _, a := range opts.Output.Annotations {
+			if a.GetPath() == "" {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "path is required"})
+				return
+			}
+		}
+
+		batches = append(batches, len(opts.Output.Annotations))
+
+		writeJSON(w, http.StatusOK, &github.CheckRun{ID: github.Int64(42), Name: &opts.Name})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	id, err := f.client(srv).CreateCheckRun(ctx, "jumppad-labs", "daggerverse", "6976eb3f392256c384e87094853853f90c64ca68", "tests", "", "failure", "", "", reports, "")
+	if err != nil {
+		return err
+	}
+
+	if id != 42 {
+		return fmt.Errorf("expected check run id 42, got %d", id)
+	}
+
+	if conclusion != "failure" {
+		return fmt.Errorf("expected conclusion failure, got %q", conclusion)
+	}
+
+	if fmt.Sprint(batches) != "[50 50 21]" {
+		return fmt.Errorf("expected annotations in batches of [50 50 21], got %v", batches)
+	}
+
+	log.Info("PASS", "test", "create check run")
+
+	return nil
+}
+
+// example: dagger call ftest-upsert-pull-request-comment
+func (m *Github) FTestUpsertPullRequestComment(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// the first comment quotes the version comment, it must not be updated
+	comments := map[int64]*github.IssueComment{
+		1: {ID: github.Int64(1), Body: github.String("> " + commentMarker("version") + "\n> Next version: 1.1.0\n\nWhy is this a minor release?")},
+	}
+	nextID := int64(2)
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/commits/{sha}/pulls", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []*github.PullRequest{testPR(3), testPR(7)})
+	})
+	f.handle("GET /repos/{owner}/{repo}/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		list := []*github.IssueComment{}
+		for i := int64(1); i < nextID; i++ {
+			list = append(list, comments[i])
+		}
+
+		writeJSON(w, http.StatusOK, list)
+	})
+	f.handle("POST /repos/{owner}/{repo}/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		c := &github.IssueComment{}
+		json.NewDecoder(r.Body).Decode(c)
+
+		c.ID = github.Int64(nextID)
+		comments[nextID] = c
+		nextID++
+
+		writeJSON(w, http.StatusCreated, c)
+	})
+	f.handle("PATCH /repos/{owner}/{repo}/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		c := &github.IssueComment{}
+		json.NewDecoder(r.Body).Decode(c)
+
+		comments[id].Body = c.Body
+		writeJSON(w, http.StatusOK, comments[id])
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	sha := "6976eb3f392256c384e87094853853f90c64ca68"
+
+	first, err := f.client(srv).UpsertCommitPullRequestComment(ctx, "jumppad-labs", "daggerverse", sha, "version", "Next version: 1.2.0")
+	if err != nil {
+		return err
+	}
+
+	second, err := f.client(srv).UpsertCommitPullRequestComment(ctx, "jumppad-labs", "daggerverse", sha, "version", "Next version: 1.3.0")
+	if err != nil {
+		return err
+	}
+
+	other, err := f.client(srv).UpsertPullRequestComment(ctx, "jumppad-labs", "daggerverse", 7, "tests", "All tests passed")
+	if err != nil {
+		return err
+	}
+
+	if first != 2 || second != 2 {
+		return fmt.Errorf("expected the version comment to be created and then updated, got ids %d and %d", first, second)
+	}
+
+	if other != 3 {
+		return fmt.Errorf("expected a new comment for a different key, got id %d", other)
+	}
+
+	if !strings.HasSuffix(comments[2].GetBody(), "Next version: 1.3.0") || !strings.HasSuffix(comments[1].GetBody(), "minor release?") {
+		return fmt.Errorf("expected the comment to be updated, got %q", comments[2].GetBody())
+	}
+
+	log.Info("PASS", "test", "upsert pull request comment")
+
+	return nil
+}
+
+// example: dagger call ftest-dispatch-workflow
+func (m *Github) FTestDispatchWorkflow(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	runs := []*github.WorkflowRun{
+		{ID: github.Int64(100), Status: github.String("completed"), Conclusion: github.String("failure")},
+	}
+	polls := 0
+	dispatched := map[string]interface{}{}
+
+	logs := &bytes.Buffer{}
+	zw := zip.NewWriter(logs)
+	w, _ := zw.Create("release/1_Publish.txt")
+	w.Write([]byte("published"))
+	zw.Close()
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/actions/workflows/{workflow}/runs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.WorkflowRuns{TotalCount: github.Int(len(runs)), WorkflowRuns: runs})
+	})
+	f.handle("POST /repos/{owner}/{repo}/actions/workflows/{workflow}/dispatches", func(w http.ResponseWriter, r *http.Request) {
+		event := github.CreateWorkflowDispatchEventRequest{}
+		json.NewDecoder(r.Body).Decode(&event)
+		dispatched = event.Inputs
+
+		runs = append(runs, &github.WorkflowRun{ID: github.Int64(101), Status: github.String("queued")})
+		w.WriteHeader(http.StatusNoContent)
+	})
+	f.handle("GET /repos/{owner}/{repo}/actions/runs/101", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+
+		run := &github.WorkflowRun{ID: github.Int64(101), Status: github.String("in_progress")}
+		if polls > 1 {
+			run.Status = github.String("completed")
+			run.Conclusion = github.String("success")
+		}
+
+		writeJSON(w, http.StatusOK, run)
+	})
+	f.handle("GET /repos/{owner}/{repo}/actions/runs/101/logs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+r.Host+"/logs.zip", http.StatusFound)
+	})
+	f.handle("GET /logs.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(logs.Bytes())
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	run, err := f.client(srv).DispatchWorkflow(ctx, "jumppad-labs", "homebrew-repo", "release.yaml", "main", []string{"version=1.2.0"}, 60)
+	if err != nil {
+		return err
+	}
+
+	if run.ID != 101 || run.Conclusion != "success" {
+		return fmt.Errorf("expected run 101 to succeed, got run %d with conclusion %q", run.ID, run.Conclusion)
+	}
+
+	if dispatched["version"] != "1.2.0" {
+		return fmt.Errorf("expected the version input to be dispatched, got %v", dispatched)
+	}
+
+	contents, err := run.Logs.File("release/1_Publish.txt").Contents(ctx)
+	if err != nil {
+		return err
+	}
+
+	if contents != "published" {
+		return fmt.Errorf("unexpected log contents %q", contents)
+	}
+
+	log.Info("PASS", "test", "dispatch workflow")
+
+	return nil
+}
+
+// example: dagger call ftest-retries
+func (m *Github) FTestRetries(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	limited := 0
+	notModified := 0
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/commits/{sha}/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("repo") == "missing" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		// rate limit the first request, the module should wait and try again
+		if limited == 0 {
+			limited++
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"message": "secondary rate limit"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, []*github.PullRequest{testPR(1, "minor")})
+	})
+	f.handle("GET /repos/{owner}/{repo}/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"tags-v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"tags-v1"`)
+		writeJSON(w, http.StatusOK, []*github.RepositoryTag{{Name: github.String("v1.2.0")}})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	sha := "6976eb3f392256c384e87094853853f90c64ca68"
+
+	for i := 0; i < 2; i++ {
+		v, err := f.client(srv).NextVersionFromAssociatedPRLabel(ctx, "jumppad-labs", "daggerverse", sha, "major", "minor", "patch", nil, "", "")
+		if err != nil {
+			return err
+		}
+
+		if v != "1.3.0" {
+			return fmt.Errorf("expected version 1.3.0, got %s", v)
+		}
+	}
+
+	if limited != 1 {
+		return fmt.Errorf("expected the rate limited request to be retried")
+	}
+
+	if notModified != 1 {
+		return fmt.Errorf("expected the second tags request to use the ETag, got %d not modified responses", notModified)
+	}
+
+	_, err := f.client(srv).NextVersionFromAssociatedPRLabel(ctx, "jumppad-labs", "missing", sha, "major", "minor", "patch", nil, "", "")
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("expected a not found error, got %v", err)
+	}
+
+	log.Info("PASS", "test", "retries")
+
+	return nil
+}
+
+// example: dagger call ftest-deployments
+func (m *Github) FTestDeployments(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	deployments := []*github.Deployment{
+		{ID: github.Int64(1), Environment: github.String("production")},
+		{ID: github.Int64(2), Environment: github.String("production")},
+	}
+	statuses := map[int64][]*github.DeploymentStatus{
+		1: {{State: github.String("success")}},
+		2: {{State: github.String("failure")}},
+	}
+	created := map[string]any{}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/deployments", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, deployments)
+	})
+	f.handle("POST /repos/{owner}/{repo}/deployments", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&created)
+
+		if created["auto_merge"] == true {
+			writeJSON(w, http.StatusAccepted, map[string]string{"message": "Auto-merged main into feature on deployment."})
+			return
+		}
+
+		d := &github.Deployment{ID: github.Int64(3), SHA: github.String("6976eb3f392256c384e87094853853f90c64ca68")}
+		deployments = append([]*github.Deployment{d}, deployments...)
+
+		writeJSON(w, http.StatusCreated, d)
+	})
+	f.handle("GET /repos/{owner}/{repo}/deployments/{id}/statuses", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		writeJSON(w, http.StatusOK, statuses[id])
+	})
+	f.handle("POST /repos/{owner}/{repo}/deployments/{id}/statuses", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+		s := &github.DeploymentStatus{}
+		json.NewDecoder(r.Body).Decode(s)
+
+		// the newest status is returned first
+		statuses[id] = append([]*github.DeploymentStatus{s}, statuses[id]...)
+		writeJSON(w, http.StatusCreated, s)
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	gh := f.client(srv)
+
+	id, err := gh.CreateDeployment(ctx, "jumppad-labs", "daggerverse", "main", "production", "", `{"service":"api"}`, "deploy", false, true, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if id != 3 {
+		return fmt.Errorf("expected deployment 3, got %d", id)
+	}
+
+	// when the default branch is merged into the ref no deployment is created
+	_, err = gh.CreateDeployment(ctx, "jumppad-labs", "daggerverse", "feature", "staging", "", "", "deploy", false, false, true, nil)
+	if err == nil || !strings.Contains(err.Error(), "merged the default branch") {
+		return fmt.Errorf("expected an error when the default branch is merged, got %v", err)
+	}
+
+	if fmt.Sprint(created["payload"]) != "map[service:api]" || fmt.Sprint(created["required_contexts"]) != "[]" {
+		return fmt.Errorf("expected the payload to be sent and the checks to be skipped, got %v", created)
+	}
+
+	err = gh.SetDeploymentStatus(ctx, "jumppad-labs", "daggerverse", id, "success", "", "https://api.jumppad.dev", "", "")
+	if err != nil {
+		return err
+	}
+
+	// a newer deployment from another pipeline finished before this one
+	deployments = append([]*github.Deployment{{ID: github.Int64(4), Environment: github.String("production")}}, deployments...)
+	statuses[4] = []*github.DeploymentStatus{{State: github.String("success")}}
+
+	count, err := gh.DeactivateDeployments(ctx, "jumppad-labs", "daggerverse", "production", id)
+	if err != nil {
+		return err
+	}
+
+	if count != 1 || statuses[1][0].GetState() != "inactive" {
+		return fmt.Errorf("expected only the earlier successful deployment to be deactivated, deactivated %d", count)
+	}
+
+	if statuses[4][0].GetState() != "success" {
+		return fmt.Errorf("expected the newer deployment to stay active, got %v", statuses[4][0])
+	}
+
+	if statuses[3][0].GetState() != "success" || statuses[3][0].GetEnvironmentURL() != "https://api.jumppad.dev" {
+		return fmt.Errorf("expected the new deployment to stay active, got %v", statuses[3][0])
+	}
+
+	log.Info("PASS", "test", "deployments")
+
+	return nil
+}
+
+// example: dagger call ftest-oidc
+func (m *Github) FTestOIDC(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]any{
+		"keys": []map[string]string{{
+			"kid": "test-key",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	f := newFakeGitHub()
+	srv := f.start()
+	defer srv.Close()
+
+	jwt, err := signJWT(key, "test-key", map[string]any{
+		"iss":        srv.URL,
+		"sub":        "repo:jumppad-labs/daggerverse:ref:refs/heads/main",
+		"aud":        "sts.amazonaws.com",
+		"exp":        time.Now().Add(5 * time.Minute).Unix(),
+		"repository": "jumppad-labs/daggerverse",
+		"ref":        "refs/heads/main",
+		"workflow":   "Build",
+		"sha":        "6976eb3f392256c384e87094853853f90c64ca68",
+	})
+	if err != nil {
+		return err
+	}
+
+	f.handle("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer request-token" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			return
+		}
+
+		if r.URL.Query().Get("audience") == "" {
+			writeJSON(w, http.StatusOK, map[string]string{"count": "1"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"value": jwt})
+	})
+	f.handle("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"jwks_uri": srv.URL + "/.well-known/jwks"})
+	})
+	f.handle("GET /.well-known/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, keys)
+	})
+
+	requestToken := dag.SetSecret("actions-request-token", "request-token")
+
+	// a response without a value should return an error rather than panic
+	_, err = m.GetOIDCToken(ctx, requestToken, srv.URL+"/token", "")
+	if err == nil {
+		return fmt.Errorf("expected an error for a response without a token")
+	}
+
+	token, err := m.GetOIDCToken(ctx, requestToken, srv.URL+"/token", "sts.amazonaws.com")
+	if err != nil {
+		return err
+	}
+
+	claims, err := m.DecodeOIDCClaims(ctx, token, nil, srv.URL, "sts.amazonaws.com")
+	if err != nil {
+		return err
+	}
+
+	if claims.Repository != "jumppad-labs/daggerverse" || claims.Ref != "refs/heads/main" || claims.Workflow != "Build" {
+		return fmt.Errorf("unexpected claims %+v", claims)
+	}
+
+	_, err = m.DecodeOIDCClaims(ctx, token, nil, srv.URL, "vault")
+	if err == nil {
+		return fmt.Errorf("expected an error for the wrong audience")
+	}
+
+	// a token signed by a different key must be rejected
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(jwt, ".")
+	forged, _ := signJWT(other, "test-key", map[string]any{"iss": srv.URL, "exp": time.Now().Add(time.Minute).Unix()})
+	forged = parts[0] + "." + parts[1] + "." + strings.Split(forged, ".")[2]
+
+	jwks, _ := json.Marshal(keys)
+	_, err = m.DecodeOIDCClaims(ctx, dag.SetSecret("forged-token", forged), dag.Directory().WithNewFile("jwks.json", string(jwks)).File("jwks.json"), srv.URL, "")
+	if err == nil {
+		return fmt.Errorf("expected an error for a token with an invalid signature")
+	}
+
+	log.Info("PASS", "test", "oidc")
+
+	return nil
+}
+
+// example: dagger call ftest-get-contents
+func (m *Github) FTestGetContents(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	blobs := map[string]string{
+		"b-readme":  "# Homebrew tap",
+		"b-formula": strings.Repeat("# jumppad formula\n", 100000),
+		"b-script":  "#!/bin/sh\necho installed",
+	}
+
+	trees := map[string][]*github.TreeEntry{
+		"t-root": {
+			{Path: github.String("README.md"), Mode: github.String("100644"), Type: github.String("blob"), SHA: github.String("b-readme")},
+			{Path: github.String("Formula"), Mode: github.String("040000"), Type: github.String("tree"), SHA: github.String("t-formula")},
+		},
+		"t-formula": {
+			{Path: github.String("jumppad.rb"), Mode: github.String("100644"), Type: github.String("blob"), SHA: github.String("b-formula")},
+			{Path: github.String("bin"), Mode: github.String("040000"), Type: github.String("tree"), SHA: github.String("t-bin")},
+		},
+	}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/contents/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("path") {
+		case "README.md":
+			writeJSON(w, http.StatusOK, &github.RepositoryContent{
+				Type:     github.String("file"),
+				Name:     github.String("README.md"),
+				SHA:      github.String("b-readme"),
+				Encoding: github.String("base64"),
+				Content:  github.String(base64.StdEncoding.EncodeToString([]byte(blobs["b-readme"]))),
+			})
+		case "Formula/jumppad.rb":
+			// the contents API does not return files over 1MB
+			writeJSON(w, http.StatusOK, &github.RepositoryContent{
+				Type:     github.String("file"),
+				Name:     github.String("jumppad.rb"),
+				SHA:      github.String("b-formula"),
+				Size:     github.Int(len(blobs["b-formula"])),
+				Encoding: github.String("none"),
+			})
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		}
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/blobs/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(blobs[r.PathValue("sha")]))
+	})
+	f.handle("GET /repos/{owner}/{repo}/commits/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("c-main"))
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/commits/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Commit{SHA: github.String("c-main"), Tree: &github.Tree{SHA: github.String("t-root")}})
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/trees/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		sha := r.PathValue("sha")
+		entries := trees[sha]
+
+		if sha == "t-formula" && r.URL.Query().Get("recursive") != "" {
+			entries = append(entries, &github.TreeEntry{
+				Path: github.String("bin/install.sh"), Mode: github.String("100755"), Type: github.String("blob"), SHA: github.String("b-script"),
+			})
+		}
+
+		writeJSON(w, http.StatusOK, &github.Tree{SHA: github.String(sha), Entries: entries})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	gh := f.client(srv)
+
+	readme, err := gh.GetFile(ctx, "jumppad-labs", "homebrew-repo", "README.md", "main")
+	if err != nil {
+		return err
+	}
+
+	contents, err := readme.Contents(ctx)
+	if err != nil {
+		return err
+	}
+
+	if contents != blobs["b-readme"] {
+		return fmt.Errorf("unexpected contents for README.md %q", contents)
+	}
+
+	formula, err := gh.GetFile(ctx, "jumppad-labs", "homebrew-repo", "Formula/jumppad.rb", "")
+	if err != nil {
+		return err
+	}
+
+	size, err := formula.Size(ctx)
+	if err != nil {
+		return err
+	}
+
+	if size != len(blobs["b-formula"]) {
+		return fmt.Errorf("expected the large file to be fetched from the blob API, got %d bytes", size)
+	}
+
+	dir, err := gh.GetDirectory(ctx, "jumppad-labs", "homebrew-repo", "Formula", "")
+	if err != nil {
+		return err
+	}
+
+	entries, err := dir.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(entries, ",") != "bin,jumppad.rb" {
+		return fmt.Errorf("unexpected directory entries %v", entries)
+	}
+
+	script, err := dir.File("bin/install.sh").Contents(ctx)
+	if err != nil {
+		return err
+	}
+
+	if script != blobs["b-script"] {
+		return fmt.Errorf("unexpected contents for bin/install.sh %q", script)
+	}
+
+	_, err = gh.GetDirectory(ctx, "jumppad-labs", "homebrew-repo", "Casks", "")
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("expected a not found error for a missing directory, got %v", err)
+	}
+
+	log.Info("PASS", "test", "get contents")
+
+	return nil
+}
+
+// example: dagger call ftest-release-checksums
+func (m *Github) FTestReleaseChecksums(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	uploaded := map[string]string{}
+
+	f := newFakeGitHub()
+	f.handle("POST /repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.RepositoryRelease{ID: github.Int64(10)})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/tags", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.Tag{SHA: github.String("t-v0.1.0")})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.Reference{})
+	})
+	f.handle("POST /repos/{owner}/{repo}/releases/10/assets", func(w http.ResponseWriter, r *http.Request) {
+		buf := &bytes.Buffer{}
+		buf.ReadFrom(r.Body)
+
+		name := r.URL.Query().Get("name")
+		uploaded[name] = buf.String()
+
+		writeJSON(w, http.StatusCreated, &github.ReleaseAsset{Name: github.String(name)})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	files := dag.Directory().
+		WithNewFile("linux/jumppad", "linux binary").
+		WithNewFile("darwin/jumppad", "darwin binary")
+
+	err := f.client(srv).CreateRelease(ctx, "jumppad-labs", "jumppad", "v0.1.0", "6976eb3f392256c384e87094853853f90c64ca68", "", files, false, false, "", false, false, "", false, "", true, nil, nil, "cosign", "Create new release", "", "")
+	if err != nil {
+		return err
+	}
+
+	expected := ""
+	for _, name := range []string{"darwin_jumppad", "linux_jumppad"} {
+		sum := sha256.Sum256([]byte(uploaded[name]))
+		expected += fmt.Sprintf("%x  %s\n", sum, name)
+	}
+
+	if uploaded["SHA256SUMS"] != expected {
+		return fmt.Errorf("unexpected checksums %q, expected %q", uploaded["SHA256SUMS"], expected)
+	}
+
+	// generated files must not replace files with the same name
+	for _, sig := range []string{"SHA256SUMS", "SHA256SUMS.sig"} {
+		err = f.client(srv).CreateRelease(ctx, "jumppad-labs", "jumppad", "v0.1.0", "6976eb3f392256c384e87094853853f90c64ca68", "", files.WithNewFile(sig, "signature"), false, false, "", false, false, "", false, "", true, dag.SetSecret("cosign-key", "key"), nil, "cosign", "Create new release", "", "")
+		if err == nil || !strings.Contains(err.Error(), "would both be uploaded") {
+			return fmt.Errorf("expected an error for a file named %s, got %v", sig, err)
+		}
+	}
+
+	log.Info("PASS", "test", "release checksums")
+
+	return nil
+}
+
+// example: dagger call ftest-upsert-release
+func (m *Github) FTestUpsertRelease(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var edit map[string]any
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.RepositoryRelease{ID: github.Int64(10), TagName: github.String(r.PathValue("tag")), Draft: github.Bool(true)})
+	})
+	f.handle("PATCH /repos/{owner}/{repo}/releases/10", func(w http.ResponseWriter, r *http.Request) {
+		edit = map[string]any{}
+		json.NewDecoder(r.Body).Decode(&edit)
+
+		writeJSON(w, http.StatusOK, &github.RepositoryRelease{ID: github.Int64(10)})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/10/assets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []*github.ReleaseAsset{})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	gh := f.client(srv)
+
+	// re-running the release must not publish the draft or replace the notes
+	err := gh.CreateRelease(ctx, "jumppad-labs", "jumppad", "v0.1.0", "6976eb3f392256c384e87094853853f90c64ca68", "", nil, false, true, "", false, false, "", false, "", false, nil, nil, "cosign", "Create new release", "", "")
+	if err != nil {
+		return err
+	}
+
+	if len(edit) != 0 {
+		return fmt.Errorf("expected no changes to the release, got %v", edit)
+	}
+
+	err = gh.CreateRelease(ctx, "jumppad-labs", "jumppad", "v0.1.0", "6976eb3f392256c384e87094853853f90c64ca68", "", nil, false, true, "Release notes", false, true, "", false, "", false, nil, nil, "cosign", "Create new release", "", "")
+	if err != nil {
+		return err
+	}
+
+	_, hasDraft := edit["draft"]
+	if edit["body"] != "Release notes" || edit["prerelease"] != true || hasDraft || edit["name"] != nil {
+		return fmt.Errorf("expected only the body and prerelease to change, got %v", edit)
+	}
+
+	log.Info("PASS", "test", "upsert release")
+
+	return nil
+}
+
+// example: dagger call ftest-create-provenance
+func (m *Github) FTestCreateProvenance(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	uploaded := map[string]string{}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.RepositoryRelease{ID: github.Int64(10), TagName: github.String(r.PathValue("tag"))})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/10/assets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []*github.ReleaseAsset{})
+	})
+	f.handle("POST /repos/{owner}/{repo}/releases/10/assets", func(w http.ResponseWriter, r *http.Request) {
+		buf := &bytes.Buffer{}
+		buf.ReadFrom(r.Body)
+
+		name := r.URL.Query().Get("name")
+		uploaded[name] = buf.String()
+
+		writeJSON(w, http.StatusCreated, &github.ReleaseAsset{Name: github.String(name)})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	// generate a cosign key pair with an empty password to sign the provenance
+	key, err := dag.Container().
+		From("cgr.dev/chainguard/cosign:latest").
+		WithWorkdir("/tmp").
+		WithEnvVariable("COSIGN_PASSWORD", "").
+		WithExec([]string{"cosign", "generate-key-pair"}).
+		File("/tmp/cosign.key").
+		Contents(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	files := dag.Directory().
+		WithNewFile("linux/jumppad", "linux binary").
+		WithNewFile("darwin/jumppad", "darwin binary")
+
+	sha := "6976eb3f392256c384e87094853853f90c64ca68"
+
+	file, err := f.client(srv).CreateProvenance(
+		ctx,
+		"jumppad-labs",
+		"jumppad",
+		sha,
+		files,
+		dag.SetSecret("cosign-key", key),
+		nil,
+		false,
+		"v0.1.0",
+		"https://github.com/jumppad-labs/daggerverse/github",
+		"https://github.com/jumppad-labs/daggerverse/github/buildtypes/dagger@v1",
+		[]string{"version=0.1.0"},
+		"",
+		"jumppad.intoto.jsonl",
+	)
+
+	if err != nil {
+		return err
+	}
+
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return err
+	}
+
+	if uploaded["jumppad.intoto.jsonl"] != contents {
+		return fmt.Errorf("expected the provenance to be attached to the release")
+	}
+
+	envelope := dsseEnvelope{}
+	if err := json.Unmarshal([]byte(contents), &envelope); err != nil {
+		return err
+	}
+
+	if len(envelope.Signatures) != 1 || envelope.Signatures[0].Sig == "" {
+		return fmt.Errorf("expected the envelope to be signed")
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return err
+	}
+
+	st := statement{}
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256([]byte("linux binary"))
+	if len(st.Subject) != 2 || st.Subject[1].Name != "linux_jumppad" || st.Subject[1].Digest["sha256"] != fmt.Sprintf("%x", sum) {
+		return fmt.Errorf("unexpected subjects %v", st.Subject)
+	}
+
+	deps := st.Predicate.BuildDefinition.ResolvedDependencies
+	if len(deps) != 1 || deps[0].Digest["gitCommit"] != sha || deps[0].URI != "git+https://github.com/jumppad-labs/jumppad@refs/tags/v0.1.0" {
+		return fmt.Errorf("unexpected source %v", deps)
+	}
+
+	if st.Predicate.BuildDefinition.ExternalParameters["version"] != "0.1.0" {
+		return fmt.Errorf("unexpected parameters %v", st.Predicate.BuildDefinition.ExternalParameters)
+	}
+
+	// a statement without subjects is invalid and must not be signed
+	_, err = f.client(srv).CreateProvenance(ctx, "jumppad-labs", "jumppad", sha, dag.Directory(), dag.SetSecret("cosign-key", key), nil, false, "", "https://github.com/jumppad-labs/daggerverse/github", "https://github.com/jumppad-labs/daggerverse/github/buildtypes/dagger@v1", nil, "", "jumppad.intoto.jsonl")
+	if err == nil {
+		return fmt.Errorf("expected an error for provenance without any files")
+	}
+
+	log.Info("PASS", "test", "create provenance")
+
+	return nil
+}
+
+// example: dagger call ftest-git-signing
+func (m *Github) FTestGitSigning(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	commit := map[string]any{}
+	tag := map[string]any{}
+	tagRef := ""
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Repository{DefaultBranch: github.String("main")})
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Reference{Object: &github.GitObject{SHA: github.String("c-parent")}})
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/commits/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Commit{SHA: github.String(r.PathValue("sha")), Tree: &github.Tree{SHA: github.String("t-parent")}})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/blobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.Blob{SHA: github.String("b-formula")})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/trees", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.Tree{SHA: github.String("t-new")})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/commits", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&commit)
+		writeJSON(w, http.StatusCreated, &github.Commit{SHA: github.String("c-new")})
+	})
+	f.handle("PATCH /repos/{owner}/{repo}/git/refs/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Reference{})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/tags", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&tag)
+		writeJSON(w, http.StatusCreated, &github.Tag{SHA: github.String("t-v0.1.0")})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		ref := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&ref)
+		tagRef = ref["ref"] + "=" + ref["sha"]
+
+		writeJSON(w, http.StatusCreated, &github.Reference{Ref: github.String(ref["ref"])})
+	})
+	f.handle("POST /repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.RepositoryRelease{ID: github.Int64(10)})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	// generate an unencrypted SSH key to sign with
+	key, err := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "openssh-keygen"}).
+		WithExec([]string{"ssh-keygen", "-t", "ed25519", "-N", "", "-C", "john@doe.com", "-f", "/tmp/signing.key"}).
+		File("/tmp/signing.key").
+		Contents(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	gh := f.client(srv).WithGitSigning(dag.SetSecret("signing-key", key), "ssh", nil)
+
+	file := dag.Directory().WithNewFile("jumppad.rb", "class Jumppad < Formula").File("jumppad.rb")
+
+	sha, err := gh.CommitFile(ctx, "jumppad-labs", "homebrew-repo", "John Doe", "john@doe.com", "Formula/jumppad.rb", "Update formula", file, "main")
+	if err != nil {
+		return err
+	}
+
+	author, _ := commit["author"].(map[string]any)
+	if sha != "c-new" || commit["signature"] == nil || commit["signature"] == "" || author["date"] == nil {
+		return fmt.Errorf("expected a signed commit with the author date, got %v", commit)
+	}
+
+	err = gh.CreateRelease(ctx, "jumppad-labs", "homebrew-repo", "v0.1.0", "c-new", "", nil, false, false, "", false, false, "", false, "", false, nil, nil, "cosign", "Release v0.1.0", "John Doe", "john@doe.com")
+	if err != nil {
+		return err
+	}
+
+	message, _ := tag["message"].(string)
+	if !strings.HasPrefix(message, "Release v0.1.0\n") || len(message) == len("Release v0.1.0\n") {
+		return fmt.Errorf("expected the signature to be appended to the tag message, got %q", message)
+	}
+
+	if tagRef != "refs/tags/v0.1.0=t-v0.1.0" {
+		return fmt.Errorf("expected the tag ref to point to the tag object, got %s", tagRef)
+	}
+
+	// a signed tag needs the tagger to match the signing key
+	err = gh.CreateRelease(ctx, "jumppad-labs", "homebrew-repo", "v0.1.1", "c-new", "", nil, false, false, "", false, false, "", false, "", false, nil, nil, "cosign", "Release v0.1.1", "", "")
+	if err == nil {
+		return fmt.Errorf("expected an error for a signed tag without a tagger")
+	}
+
+	log.Info("PASS", "test", "git signing")
+
+	return nil
+}
+
+// example: dagger call ftest-release-existing-tag
+func (m *Github) FTestReleaseExistingTag(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sha := "6976eb3f392256c384e87094853853f90c64ca68"
+
+	refs := map[string]*github.GitObject{
+		"v0.1.0": {Type: github.String("tag"), SHA: github.String("t-v0.1.0")},
+		"v0.2.0": {Type: github.String("commit"), SHA: github.String(sha)},
+		"v0.3.0": {Type: github.String("tag"), SHA: github.String("t-v0.3.0")},
+	}
+
+	tags := map[string]string{
+		"t-v0.1.0": sha,
+		"t-v0.3.0": "c-other",
+	}
+
+	releases := 0
+
+	f := newFakeGitHub()
+	f.handle("POST /repos/{owner}/{repo}/git/tags", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, &github.Tag{SHA: github.String("t-new")})
+	})
+	f.handle("POST /repos/{owner}/{repo}/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"message": "Reference already exists",
+			"errors":  []map[string]string{{"resource": "Reference", "code": "already_exists"}},
+		})
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/ref/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Reference{Ref: github.String("refs/tags/" + r.PathValue("tag")), Object: refs[r.PathValue("tag")]})
+	})
+	f.handle("GET /repos/{owner}/{repo}/git/tags/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &github.Tag{SHA: github.String(r.PathValue("sha")), Object: &github.GitObject{SHA: github.String(tags[r.PathValue("sha")])}})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []*github.RepositoryRelease{})
+	})
+	f.handle("POST /repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		releases++
+		writeJSON(w, http.StatusCreated, &github.RepositoryRelease{ID: github.Int64(10)})
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/10/assets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []*github.ReleaseAsset{})
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	gh := f.client(srv)
+
+	// annotated and lightweight tags for the commit are used for the release, this also allows an upsert
+	// to be retried after the tag was created but the release was not
+	for _, tag := range []string{"v0.1.0", "v0.2.0"} {
+		err := gh.CreateRelease(ctx, "jumppad-labs", "jumppad", tag, sha, "", nil, false, true, "", false, false, "", false, "", false, nil, nil, "cosign", "Create new release", "", "")
+		if err != nil {
+			return fmt.Errorf("expected the existing tag %s to be used: %w", tag, err)
+		}
+	}
+
+	if releases != 2 {
+		return fmt.Errorf("expected 2 releases to be created, got %d", releases)
+	}
+
+	err := gh.CreateRelease(ctx, "jumppad-labs", "jumppad", "v0.3.0", sha, "", nil, false, true, "", false, false, "", false, "", false, nil, nil, "cosign", "Create new release", "", "")
+	if !errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), "c-other") {
+		return fmt.Errorf("expected a conflict for a tag on a different commit, got %v", err)
+	}
+
+	log.Info("PASS", "test", "release existing tag")
+
+	return nil
+}
+
+// example: dagger call ftest-sync-labels
+func (m *Github) FTestSyncLabels(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	labels := map[string]*github.Label{
+		"Bug":     {Name: github.String("Bug"), Color: github.String("d73a4a"), Description: github.String("Something isn't working")},
+		"area/ci": {Name: github.String("area/ci"), Color: github.String("ededed")},
+		"wontfix": {Name: github.String("wontfix"), Color: github.String("ffffff")},
+	}
+
+	milestones := map[int]*github.Milestone{
+		1: {Number: github.Int(1), Title: github.String("v0.9.0"), State: github.String("open")},
+		2: {Number: github.Int(2), Title: github.String("v1.0.0"), State: github.String("open"), DueOn: &github.Timestamp{Time: time.Date(2024, 12, 31, 8, 0, 0, 0, time.UTC)}},
+		3: {Number: github.Int(3), Title: github.String("backlog"), State: github.String("open")},
+	}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/labels", func(w http.ResponseWriter, r *http.Request) {
+		// split the labels over two pages to check all pages are read
+		pages := [][]*github.Label{{}, {}}
+		for i, name := range sortedKeys(labels) {
+			pages[i%2] = append(pages[i%2], labels[name])
+		}
+
+		writePage(w, r, pages)
+	})
+	f.handle("POST /repos/{owner}/{repo}/labels", func(w http.ResponseWriter, r *http.Request) {
+		l := &github.Label{}
+		json.NewDecoder(r.Body).Decode(l)
+
+		labels[l.GetName()] = l
+		writeJSON(w, http.StatusCreated, l)
+	})
+	f.handle("PATCH /repos/{owner}/{repo}/labels/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if labels[r.PathValue("name")] == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		l := &github.Label{}
+		json.NewDecoder(r.Body).Decode(l)
+
+		delete(labels, r.PathValue("name"))
+		labels[l.GetName()] = l
+		writeJSON(w, http.StatusOK, l)
+	})
+	f.handle("DELETE /repos/{owner}/{repo}/labels/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if labels[r.PathValue("name")] == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		delete(labels, r.PathValue("name"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	f.handle("GET /repos/{owner}/{repo}/milestones", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "all" {
+			writeJSON(w, http.StatusOK, []*github.Milestone{})
+			return
+		}
+
+		ms := []*github.Milestone{}
+		for _, m := range milestones {
+			ms = append(ms, m)
+		}
+
+		writeJSON(w, http.StatusOK, ms)
+	})
+	f.handle("POST /repos/{owner}/{repo}/milestones", func(w http.ResponseWriter, r *http.Request) {
+		ms := &github.Milestone{}
+		json.NewDecoder(r.Body).Decode(ms)
+
+		ms.Number = github.Int(len(milestones) + 10)
+		milestones[ms.GetNumber()] = ms
+		writeJSON(w, http.StatusCreated, ms)
+	})
+	f.handle("PATCH /repos/{owner}/{repo}/milestones/{number}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("number"))
+
+		ms := &github.Milestone{}
+		json.NewDecoder(r.Body).Decode(ms)
+
+		ms.Number = github.Int(n)
+		milestones[n] = ms
+		writeJSON(w, http.StatusOK, ms)
+	})
+	f.handle("DELETE /repos/{owner}/{repo}/milestones/{number}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("number"))
+
+		delete(milestones, n)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	gh := f.client(srv)
+
+	// without a config only the version labels are known so the other labels must not be pruned
+	_, err := gh.SyncLabels(ctx, "jumppad-labs", "jumppad", nil, true)
+	if err == nil || len(labels) != 3 {
+		return fmt.Errorf("expected an error when pruning without a config, got %v", err)
+	}
+
+	// without a config the labels used to calculate the next version are created
+	result, err := gh.SyncLabels(ctx, "jumppad-labs", "jumppad", nil, false)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(result.Created, ",") != "major,minor,patch" || len(result.Updated) > 0 || len(result.Deleted) > 0 {
+		return fmt.Errorf("expected the version labels to be created, got %+v", result)
+	}
+
+	config := dag.Directory().WithNewFile("labels.yaml", `
+labels:
+  - name: bug
+    color: "#D73A4A"
+    description: Something isn't working
+  - name: area/ci
+    color: 0e8a16
+    description: Changes to the build
+  - name: major
+    color: d73a4a
+    description: Breaking change, increments the major version
+  - name: minor
+    color: 0e8a16
+    description: New feature, increments the minor version
+  - name: patch
+    color: 1d76db
+    description: Bug fix, increments the patch version
+milestones:
+  - title: v0.9.0
+    state: closed
+  - title: v1.0.0
+    due_on: 2024-12-31
+  - title: v1.1.0
+    description: Plugins
+    due_on: 2025-03-31
+`).File("labels.yaml")
+
+	result, err = gh.SyncLabels(ctx, "jumppad-labs", "jumppad", config, true)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Created) > 0 || strings.Join(result.Updated, ",") != "bug,area/ci" || strings.Join(result.Deleted, ",") != "wontfix" {
+		return fmt.Errorf("unexpected changes to labels %+v", result)
+	}
+
+	if labels["bug"].GetColor() != "d73a4a" || labels["area/ci"].GetDescription() != "Changes to the build" || labels["Bug"] != nil {
+		return fmt.Errorf("expected labels to be updated, got %v", labels)
+	}
+
+	// syncing again should not make any changes
+	result, err = gh.SyncLabels(ctx, "jumppad-labs", "jumppad", config, true)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Created)+len(result.Updated)+len(result.Deleted) > 0 {
+		return fmt.Errorf("expected no changes to labels, got %+v", result)
+	}
+
+	// JSON config files are also supported
+	config = dag.Directory().WithNewFile("labels.json", `{"labels": [{"name": "good first issue", "color": "7057ff"}]}`).File("labels.json")
+
+	result, err = gh.SyncLabels(ctx, "jumppad-labs", "jumppad", config, false)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(result.Created, ",") != "good first issue" || labels["good first issue"] == nil || len(labels) != 6 {
+		return fmt.Errorf("expected label to be created from JSON config, got %+v", result)
+	}
+
+	invalid := dag.Directory().WithNewFile("labels.yaml", "labels:\n  - color: ffffff\n").File("labels.yaml")
+
+	_, err = gh.SyncLabels(ctx, "jumppad-labs", "jumppad", invalid, false)
+	if err == nil {
+		return fmt.Errorf("expected an error for a label without a name")
+	}
+
+	// a config without milestones must not delete every milestone
+	_, err = gh.SyncMilestones(ctx, "jumppad-labs", "jumppad", config, true)
+	if err == nil || len(milestones) != 3 {
+		return fmt.Errorf("expected an error when pruning without milestones, got %v", err)
+	}
+
+	result, err = gh.SyncMilestones(ctx, "jumppad-labs", "jumppad", config, false)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Created)+len(result.Updated)+len(result.Deleted) > 0 {
+		return fmt.Errorf("expected no changes for a config without milestones, got %+v", result)
+	}
+
+	// a config without labels must not delete every label
+	for _, c := range []string{"milestones: []\n", "labels: []\n"} {
+		_, err = gh.SyncLabels(ctx, "jumppad-labs", "jumppad", dag.Directory().WithNewFile("empty.yaml", c).File("empty.yaml"), true)
+		if err == nil || len(labels) != 6 {
+			return fmt.Errorf("expected an error when pruning without labels, got %v", err)
+		}
+	}
+
+	config = dag.Directory().WithNewFile("milestones.yaml", `
+milestones:
+  - title: v0.9.0
+    state: closed
+  - title: v1.0.0
+    due_on: 2024-12-31
+  - title: v1.1.0
+    description: Plugins
+    due_on: 2025-03-31
+`).File("milestones.yaml")
+
+	result, err = gh.SyncMilestones(ctx, "jumppad-labs", "jumppad", config, true)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(result.Created, ",") != "v1.1.0" || strings.Join(result.Updated, ",") != "v0.9.0" || strings.Join(result.Deleted, ",") != "backlog" {
+		return fmt.Errorf("unexpected changes to milestones %+v", result)
+	}
+
+	if milestones[1].GetState() != "closed" || milestones[3] != nil {
+		return fmt.Errorf("expected milestones to be updated, got %v", milestones)
+	}
+
+	log.Info("PASS", "test", "sync labels")
+
+	return nil
+}
+
+// example: dagger call ftest-prune-releases
+func (m *Github) FTestPruneReleases(ctx context.Context) error {
+	// enable debug logging
+	log.SetLevel(log.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	release := func(id int64, tag string, age int, prerelease, draft bool) *github.RepositoryRelease {
+		return &github.RepositoryRelease{
+			ID:         github.Int64(id),
+			TagName:    github.String(tag),
+			Prerelease: github.Bool(prerelease),
+			Draft:      github.Bool(draft),
+			CreatedAt:  &github.Timestamp{Time: time.Now().AddDate(0, 0, -age)},
+		}
+	}
+
+	releases := [][]*github.RepositoryRelease{
+		{
+			release(1, "v1.4.0", 2, false, true),
+			release(2, "v1.3.0-rc.3", 1, true, false),
+			release(3, "v1.3.0-rc.2", 20, true, false),
+		},
+		{
+			release(4, "v1.3.0-rc.1", 40, true, false),
+			release(5, "nightly-20240101", 60, true, false),
+			release(6, "v1.2.0", 90, false, false),
+			release(7, "v1.1.0", 120, false, false),
+		},
+	}
+
+	deletedReleases := []string{}
+	deletedTags := []string{}
+
+	f := newFakeGitHub()
+	f.handle("GET /repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, r, releases)
+	})
+	f.handle("GET /repos/{owner}/{repo}/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, releases[1][2])
+	})
+	f.handle("DELETE /repos/{owner}/{repo}/releases/{id}", func(w http.ResponseWriter, r *http.Request) {
+		deletedReleases = append(deletedReleases, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	f.handle("DELETE /repos/{owner}/{repo}/git/refs/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		// the tag for a release can be deleted separately
+		if r.PathValue("tag") == "v1.3.0-rc.1" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Reference does not exist"})
+			return
+		}
+
+		deletedTags = append(deletedTags, r.PathValue("tag"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := f.start()
+	defer srv.Close()
+
+	gh := f.client(srv)
+
+	tests := []struct {
+		name            string
+		olderThanDays   int
+		keepLast        int
+		keepPer         string
+		includeReleases bool
+		expected        string
+	}{
+		{name: "older than", olderThanDays: 30, keepPer: "minor", expected: "v1.3.0-rc.1,nightly-20240101"},
+		{name: "keep last per minor", keepLast: 1, keepPer: "minor", expected: "v1.3.0-rc.2,v1.3.0-rc.1"},
+		{name: "keep last and older than", olderThanDays: 30, keepLast: 1, keepPer: "minor", expected: "v1.3.0-rc.1,nightly-20240101"},
+		{name: "keep last per major", keepLast: 2, keepPer: "major", expected: "v1.3.0-rc.2,v1.3.0-rc.1"},
+		{name: "include releases", keepLast: 1, keepPer: "major", includeReleases: true, expected: "v1.3.0-rc.3,v1.3.0-rc.2,v1.3.0-rc.1,v1.1.0"},
+	}
+
+	for _, tc := range tests {
+		pruned, err := gh.PruneReleases(ctx, "jumppad-labs", "jumppad", tc.olderThanDays, tc.keepLast, tc.keepPer, "", tc.includeReleases, true, true)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tc.name, err)
+		}
+
+		if strings.Join(pruned, ",") != tc.expected {
+			return fmt.Errorf("%s: expected %s to be pruned, got %v", tc.name, tc.expected, pruned)
+		}
+	}
+
+	if len(deletedReleases) > 0 || len(deletedTags) > 0 {
+		return fmt.Errorf("expected a dry run not to delete releases, got %v %v", deletedReleases, deletedTags)
+	}
+
+	_, err := gh.PruneReleases(ctx, "jumppad-labs", "jumppad", 0, 0, "minor", "", false, false, false)
+	if err == nil {
+		return fmt.Errorf("expected an error when no policy is set")
+	}
+
+	pruned, err := gh.PruneReleases(ctx, "jumppad-labs", "jumppad", 10, 0, "minor", "", false, true, false)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(pruned, ",") != "v1.3.0-rc.2,v1.3.0-rc.1,nightly-20240101" {
+		return fmt.Errorf("unexpected releases pruned %v", pruned)
+	}
+
+	if strings.Join(deletedReleases, ",") != "3,4,5" || strings.Join(deletedTags, ",") != "v1.3.0-rc.2,nightly-20240101" {
+		return fmt.Errorf("unexpected releases and tags deleted %v %v", deletedReleases, deletedTags)
+	}
+
+	log.Info("PASS", "test", "prune releases")
+
+	return nil
+}
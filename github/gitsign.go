@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"main/internal/dagger"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// WithGitSigning signs the commits and tags created by the module with a GPG or SSH private key so that GitHub
+// shows them as verified, format is either `gpg` or `ssh`. Password is the passphrase for encrypted keys.
+// The key must belong to the user set as the author of commits and the tagger of tags.
+func (m *Github) WithGitSigning(
+	key *dagger.Secret,
+	// +optional
+	// +default="gpg"
+	format string,
+	// +optional
+	password *dagger.Secret,
+) *Github {
+	m.GitSigningKey = key
+	m.GitSigningFormat = format
+	m.GitSigningPassword = password
+
+	return m
+}
+
+// gitSigner returns a signer for commits and tags or nil when signing has not been configured
+func (m *Github) gitSigner(ctx context.Context) github.MessageSigner {
+	if m.GitSigningKey == nil {
+		return nil
+	}
+
+	return &gitSigner{ctx: ctx, format: m.GitSigningFormat, key: m.GitSigningKey, password: m.GitSigningPassword}
+}
+
+// gitSigner creates detached signatures for git objects using gpg or ssh-keygen in a container,
+// it implements github.MessageSigner so it can be used when creating commits
+type gitSigner struct {
+	ctx      context.Context
+	format   string
+	key      *dagger.Secret
+	password *dagger.Secret
+}
+
+// Sign writes the armored signature of the payload read from r to w
+func (s *gitSigner) Sign(w io.Writer, r io.Reader) error {
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// the payload is written to the working directory so it can be loaded into the container
+	dir, err := os.MkdirTemp(".", "git-signature-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.WriteFile(filepath.Join(dir, "payload"), payload, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write payload: %w", err)
+	}
+
+	ctr := dag.Container().
+		From("alpine:latest").
+		WithMountedFile("/tmp/payload", dag.CurrentModule().Workdir(dir).File("payload"))
+
+	switch s.format {
+	case "gpg":
+		passphrase := "--passphrase ''"
+		if s.password != nil {
+			ctr = ctr.WithMountedSecret("/keys/password", s.password)
+			passphrase = "--passphrase-file /keys/password"
+		}
+
+		gpg := "gpg --batch --yes --pinentry-mode loopback " + passphrase
+
+		ctr = ctr.
+			WithExec([]string{"apk", "add", "--no-cache", "gnupg"}).
+			WithMountedSecret("/keys/signing.key", s.key).
+			WithEnvVariable("GNUPGHOME", "/tmp/gnupg").
+			WithExec([]string{
+				"sh", "-c",
+				fmt.Sprintf("mkdir -m 700 -p /tmp/gnupg && %s --import /keys/signing.key && %s --armor --detach-sign --output /tmp/payload.sig /tmp/payload", gpg, gpg),
+			})
+
+	case "ssh":
+		ctr = ctr.
+			WithExec([]string{"apk", "add", "--no-cache", "openssh-keygen"}).
+			WithMountedSecret("/keys/signing.key", s.key, dagger.ContainerWithMountedSecretOpts{Mode: 0600})
+
+		// ssh-keygen only reads the passphrase from a terminal or an askpass program
+		if s.password != nil {
+			ctr = ctr.
+				WithSecretVariable("SIGNING_KEY_PASSWORD", s.password).
+				WithNewFile("/usr/local/bin/askpass", "#!/bin/sh\nprintf '%s\\n' \"$SIGNING_KEY_PASSWORD\"\n", dagger.ContainerWithNewFileOpts{Permissions: 0755}).
+				WithEnvVariable("SSH_ASKPASS", "/usr/local/bin/askpass").
+				WithEnvVariable("SSH_ASKPASS_REQUIRE", "force")
+		}
+
+		ctr = ctr.WithExec([]string{"ssh-keygen", "-Y", "sign", "-f", "/keys/signing.key", "-n", "git", "/tmp/payload"})
+
+	default:
+		return fmt.Errorf("unsupported signing format %q, expected gpg or ssh", s.format)
+	}
+
+	sig, err := ctr.File("/tmp/payload.sig").Contents(s.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to sign git object: %w", err)
+	}
+
+	_, err = io.WriteString(w, sig)
+
+	return err
+}
+
+// signTag returns the message for a tag object with the signature appended, git stores the signature of a tag
+// at the end of the message. The payload is the tag object GitHub creates from the same fields, so the tagger
+// date must be sent unchanged when the tag is created.
+func signTag(signer github.MessageSigner, t *github.Tag) (string, error) {
+	message := t.GetMessage()
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	date := t.GetTagger().GetDate()
+
+	payload := fmt.Sprintf(
+		"object %s\ntype %s\ntag %s\ntagger %s <%s> %d %s\n\n%s",
+		t.GetObject().GetSHA(),
+		t.GetObject().GetType(),
+		t.GetTag(),
+		t.GetTagger().GetName(),
+		t.GetTagger().GetEmail(),
+		date.Unix(),
+		date.Format("-0700"),
+		message,
+	)
+
+	sb := &strings.Builder{}
+	if err := signer.Sign(sb, strings.NewReader(payload)); err != nil {
+		return "", err
+	}
+
+	return message + sb.String(), nil
+}
+
+// signingTime returns the current time for the author or tagger of a signed object, git only
+// stores the time to the second so the time is truncated to match the signed payload
+func signingTime() *github.Timestamp {
+	return &github.Timestamp{Time: time.Now().UTC().Truncate(time.Second)}
+}
@@ -10,6 +10,7 @@ require (
 	github.com/vektah/gqlparser/v2 v2.5.30
 	golang.org/x/oauth2 v0.30.0
 	golang.org/x/sync v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
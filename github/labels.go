@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"main/internal/dagger"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+	"gopkg.in/yaml.v3"
+)
+
+// SyncResult lists the changes made to a repository when syncing labels or milestones
+type SyncResult struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+// repositoryConfig is the definition of the labels and milestones for a repository, the same file
+// can be used for SyncLabels and SyncMilestones
+type repositoryConfig struct {
+	Labels     []labelConfig     `yaml:"labels"`
+	Milestones []milestoneConfig `yaml:"milestones"`
+}
+
+type labelConfig struct {
+	Name        string `yaml:"name"`
+	Color       string `yaml:"color"`
+	Description string `yaml:"description"`
+}
+
+type milestoneConfig struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	// State is open or closed, defaults to open
+	State string `yaml:"state"`
+	// DueOn is the date the milestone is due, i.e. 2024-12-31
+	DueOn string `yaml:"due_on"`
+}
+
+// defaultLabels are created when SyncLabels is called without a config, they are the labels
+// used by NextVersionFromAssociatedPRLabel to calculate the next version
+var defaultLabels = []labelConfig{
+	{Name: "major", Color: "d73a4a", Description: "Breaking change, increments the major version"},
+	{Name: "minor", Color: "0e8a16", Description: "New feature, increments the minor version"},
+	{Name: "patch", Color: "1d76db", Description: "Bug fix, increments the patch version"},
+}
+
+// SyncLabels creates and updates the labels in a repository to match the labels defined in the YAML or JSON
+// config file, labels are matched by name ignoring case. When prune is set labels that are not in the config are
+// deleted, a config without any labels can not be pruned. If no config is set the `major`, `minor` and `patch`
+// labels used by NextVersionFromAssociatedPRLabel are created, prune can only be used with a config.
+//
+// example config:
+//
+//	labels:
+//	  - name: major
+//	    color: d73a4a
+//	    description: Breaking change
+func (m *Github) SyncLabels(
+	ctx context.Context,
+	owner,
+	repo string,
+	// +optional
+	config *dagger.File,
+	// +optional
+	prune bool,
+) (*SyncResult, error) {
+	labels := defaultLabels
+
+	// the default labels are only the version labels, pruning would delete every other label
+	if config == nil && prune {
+		return nil, fmt.Errorf("prune requires a config, refusing to prune all the labels in the repository")
+	}
+
+	if config != nil {
+		rc, err := readRepositoryConfig(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+
+		// an empty list would delete every label in the repository
+		if prune && len(rc.Labels) == 0 {
+			return nil, fmt.Errorf("config does not define any labels, refusing to prune all the labels in the repository")
+		}
+
+		labels = rc.Labels
+	}
+
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := listLabels(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Created: []string{}, Updated: []string{}, Deleted: []string{}}
+	defined := map[string]bool{}
+
+	for _, l := range labels {
+		if l.Name == "" {
+			return nil, fmt.Errorf("labels must have a name")
+		}
+
+		key := strings.ToLower(l.Name)
+		if defined[key] {
+			return nil, fmt.Errorf("label %q is defined more than once", l.Name)
+		}
+		defined[key] = true
+
+		label := &github.Label{
+			Name:        github.String(l.Name),
+			Color:       github.String(strings.ToLower(strings.TrimPrefix(l.Color, "#"))),
+			Description: github.String(l.Description),
+		}
+
+		current, ok := existing[key]
+		if !ok {
+			_, _, err := client.Issues.CreateLabel(ctx, owner, repo, label)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create label %s: %w", l.Name, classifyError(err))
+			}
+
+			log.Debug("Created label", "name", l.Name)
+			result.Created = append(result.Created, l.Name)
+
+			continue
+		}
+
+		if current.GetName() == label.GetName() && current.GetColor() == label.GetColor() && current.GetDescription() == label.GetDescription() {
+			continue
+		}
+
+		_, _, err := client.Issues.EditLabel(ctx, owner, repo, url.PathEscape(current.GetName()), label)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update label %s: %w", l.Name, classifyError(err))
+		}
+
+		log.Debug("Updated label", "name", l.Name)
+		result.Updated = append(result.Updated, l.Name)
+	}
+
+	if !prune {
+		return result, nil
+	}
+
+	for _, key := range sortedKeys(existing) {
+		if defined[key] {
+			continue
+		}
+
+		l := existing[key]
+		_, err := client.Issues.DeleteLabel(ctx, owner, repo, url.PathEscape(l.GetName()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete label %s: %w", l.GetName(), classifyError(err))
+		}
+
+		log.Debug("Deleted label", "name", l.GetName())
+		result.Deleted = append(result.Deleted, l.GetName())
+	}
+
+	return result, nil
+}
+
+// SyncMilestones creates and updates the milestones in a repository to match the milestones defined in the YAML
+// or JSON config file, milestones are matched by title. When prune is set milestones that are not in the config
+// are deleted, issues in a deleted milestone are not deleted. A config without any milestones can not be pruned.
+//
+// example config:
+//
+//	milestones:
+//	  - title: v1.0.0
+//	    description: First stable release
+//	    due_on: 2024-12-31
+//	  - title: v0.9.0
+//	    state: closed
+func (m *Github) SyncMilestones(
+	ctx context.Context,
+	owner,
+	repo string,
+	config *dagger.File,
+	// +optional
+	prune bool,
+) (*SyncResult, error) {
+	rc, err := readRepositoryConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty list would delete every milestone in the repository
+	if prune && len(rc.Milestones) == 0 {
+		return nil, fmt.Errorf("config does not define any milestones, refusing to prune all the milestones in the repository")
+	}
+
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := listMilestones(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Created: []string{}, Updated: []string{}, Deleted: []string{}}
+	defined := map[string]bool{}
+
+	for _, mc := range rc.Milestones {
+		if mc.Title == "" {
+			return nil, fmt.Errorf("milestones must have a title")
+		}
+
+		if defined[mc.Title] {
+			return nil, fmt.Errorf("milestone %q is defined more than once", mc.Title)
+		}
+		defined[mc.Title] = true
+
+		milestone, err := newMilestone(mc)
+		if err != nil {
+			return nil, err
+		}
+
+		current, ok := existing[mc.Title]
+		if !ok {
+			_, _, err := client.Issues.CreateMilestone(ctx, owner, repo, milestone)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create milestone %s: %w", mc.Title, classifyError(err))
+			}
+
+			log.Debug("Created milestone", "title", mc.Title)
+			result.Created = append(result.Created, mc.Title)
+
+			continue
+		}
+
+		if milestoneMatches(current, milestone) {
+			continue
+		}
+
+		_, _, err = client.Issues.EditMilestone(ctx, owner, repo, current.GetNumber(), milestone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update milestone %s: %w", mc.Title, classifyError(err))
+		}
+
+		log.Debug("Updated milestone", "title", mc.Title)
+		result.Updated = append(result.Updated, mc.Title)
+	}
+
+	if !prune {
+		return result, nil
+	}
+
+	for _, title := range sortedKeys(existing) {
+		if defined[title] {
+			continue
+		}
+
+		_, err := client.Issues.DeleteMilestone(ctx, owner, repo, existing[title].GetNumber())
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete milestone %s: %w", title, classifyError(err))
+		}
+
+		log.Debug("Deleted milestone", "title", title)
+		result.Deleted = append(result.Deleted, title)
+	}
+
+	return result, nil
+}
+
+// readRepositoryConfig reads the labels and milestones from a YAML or JSON file, JSON is valid YAML
+// so both formats are read with the YAML decoder
+func readRepositoryConfig(ctx context.Context, config *dagger.File) (*repositoryConfig, error) {
+	data, err := config.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	rc := &repositoryConfig{}
+	err = yaml.Unmarshal([]byte(data), rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return rc, nil
+}
+
+// newMilestone converts the config to a milestone, the due date can be a date or a RFC3339 time
+func newMilestone(mc milestoneConfig) (*github.Milestone, error) {
+	state := mc.State
+	if state == "" {
+		state = "open"
+	}
+
+	if state != "open" && state != "closed" {
+		return nil, fmt.Errorf("invalid state %q for milestone %s, expected open or closed", mc.State, mc.Title)
+	}
+
+	ms := &github.Milestone{
+		Title:       github.String(mc.Title),
+		Description: github.String(mc.Description),
+		State:       github.String(state),
+	}
+
+	if mc.DueOn != "" {
+		due, err := time.Parse(time.DateOnly, mc.DueOn)
+		if err != nil {
+			due, err = time.Parse(time.RFC3339, mc.DueOn)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid due date %q for milestone %s, expected a date such as 2024-12-31", mc.DueOn, mc.Title)
+		}
+
+		ms.DueOn = &github.Timestamp{Time: due}
+	}
+
+	return ms, nil
+}
+
+// milestoneMatches returns true when the existing milestone does not need to be updated, GitHub stores the due
+// date at the start of the day in the timezone of the user so only the date is compared
+func milestoneMatches(current, ms *github.Milestone) bool {
+	due := func(m *github.Milestone) string {
+		if m.DueOn == nil {
+			return ""
+		}
+
+		return m.DueOn.UTC().Format(time.DateOnly)
+	}
+
+	return current.GetDescription() == ms.GetDescription() && current.GetState() == ms.GetState() && due(current) == due(ms)
+}
+
+// listLabels returns all the labels in the repository keyed by the lower case name
+func listLabels(ctx context.Context, client *github.Client, owner, repo string) (map[string]*github.Label, error) {
+	labels := map[string]*github.Label{}
+	page := 0
+
+	for {
+		ls, resp, err := client.Issues.ListLabels(ctx, owner, repo, &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list labels: %w", classifyError(err))
+		}
+
+		for _, l := range ls {
+			labels[strings.ToLower(l.GetName())] = l
+		}
+
+		if resp.NextPage == 0 {
+			return labels, nil
+		}
+
+		page = resp.NextPage
+	}
+}
+
+// listMilestones returns all the open and closed milestones in the repository keyed by title
+func listMilestones(ctx context.Context, client *github.Client, owner, repo string) (map[string]*github.Milestone, error) {
+	milestones := map[string]*github.Milestone{}
+	page := 0
+
+	for {
+		ms, resp, err := client.Issues.ListMilestones(ctx, owner, repo, &github.MilestoneListOptions{
+			State:       "all",
+			ListOptions: github.ListOptions{Page: page, PerPage: 100},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to list milestones: %w", classifyError(err))
+		}
+
+		for _, m := range ms {
+			milestones[m.GetTitle()] = m
+		}
+
+		if resp.NextPage == 0 {
+			return milestones, nil
+		}
+
+		page = resp.NextPage
+	}
+}
+
+// sortedKeys returns the keys of the map in order so that changes are made in a predictable order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
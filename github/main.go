@@ -2,18 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"main/internal/dagger"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
-	"sort"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/Masterminds/semver"
 	"github.com/charmbracelet/log"
 	"github.com/google/go-github/v58/github"
 	"golang.org/x/oauth2"
@@ -21,17 +22,92 @@ import (
 
 type Github struct {
 	Token *dagger.Secret
+
+	// AppID is the id of the GitHub App used for authentication when set with WithAppAuth
+	AppID int
+	// InstallationID is the id of the GitHub App installation used for authentication
+	InstallationID int
+	// AppPrivateKey is the PEM encoded private key of the GitHub App
+	AppPrivateKey *dagger.Secret
+
+	// BaseURL is the URL of the GitHub API, when empty api.github.com is used
+	BaseURL string
+	// UploadURL is the URL used to upload release assets, when empty the BaseURL is used
+	UploadURL string
+
+	// GitSigningKey is the GPG or SSH private key used to sign commits and tags when set with WithGitSigning
+	GitSigningKey *dagger.Secret
+	// GitSigningFormat is the format of the signing key, `gpg` or `ssh`
+	GitSigningFormat string
+	// GitSigningPassword is the passphrase for an encrypted signing key
+	GitSigningPassword *dagger.Secret
 }
 
 // WithToken sets the GithHub token for any opeations that require it
 func (m *Github) WithToken(token *dagger.Secret) *Github {
 	m.Token = token
+	m.AppPrivateKey = nil
+
+	return m
+}
+
+// WithAppAuth authenticates as an installation of a GitHub App rather than using a token, short lived
+// installation tokens are created from the app private key and are automatically refreshed when they expire
+func (m *Github) WithAppAuth(appID, installationID int, privateKey *dagger.Secret) *Github {
+	m.AppID = appID
+	m.InstallationID = installationID
+	m.AppPrivateKey = privateKey
+	m.Token = nil
+
+	return m
+}
+
+// WithBaseURL sets the URL of the GitHub API used for all operations, the URL is used as is and can point
+// to any server implementing the GitHub API. If uploadURL is not set, release assets are uploaded to baseURL.
+func (m *Github) WithBaseURL(
+	baseURL string,
+	// +optional
+	uploadURL string,
+) *Github {
+	m.BaseURL = baseURL
+	m.UploadURL = uploadURL
 
 	return m
 }
 
-// TagRepository creates a tag for a repository with the given commit sha and an optional list of files
-// note: only the top level files in the directory will be uploaded, this function does not support subdirectories
+// WithEnterpriseURL configures the module to use a GitHub Enterprise Server instance,
+// i.e. `https://github.example.com`, the API and upload paths are added to the URL
+func (m *Github) WithEnterpriseURL(serverURL string) *Github {
+	serverURL = strings.TrimSuffix(serverURL, "/")
+
+	m.BaseURL = serverURL + "/api/v3/"
+	m.UploadURL = serverURL + "/api/uploads/"
+
+	return m
+}
+
+// CreateRelease creates a tag for a repository with the given commit sha and an optional directory of files
+// to attach to the release. Files in subdirectories are uploaded as well, by default the asset name is
+// prefixed with the relative path of the file (i.e. `linux/amd64/app` becomes `linux_amd64_app`), when
+// flatten is set only the file name is used. An error is returned if two files would produce the same asset name.
+//
+// When upsert is set an existing release for the tag is updated instead of returning an error, assets with
+// identical content are skipped and assets that have changed or were only partially uploaded are replaced.
+// This allows a failed release to be safely re-run. Only the name, body, draft, prerelease and makeLatest
+// values that are set are changed on the existing release, so a draft release stays a draft.
+//
+// The release can be created as a draft or prerelease, makeLatest can be one of `true`, `false` or `legacy`.
+// When generateNotes is set GitHub generates the release notes for the changes between previousTag, or the
+// previous release when not set, and the new tag. Any body is placed before the generated notes.
+// Draft releases can be published once all assets have been uploaded using PublishRelease.
+//
+// When checksums is set a SHA256SUMS file covering every asset is uploaded with the assets. Setting signingKey
+// also uploads a detached signature of the file created with signer, either `cosign` (SHA256SUMS.sig) or
+// `minisign` (SHA256SUMS.minisig), signingKeyPassword is the password for encrypted keys.
+//
+// The release tag is an annotated tag with the message tagMessage, an existing tag is used when it points to
+// sha. When WithGitSigning is set the tag is signed, signed tags require taggerName and taggerEmail to be set
+// to the owner of the signing key.
 func (m *Github) CreateRelease(
 	ctx context.Context,
 	owner,
@@ -42,127 +118,338 @@ func (m *Github) CreateRelease(
 	name string,
 	// +optional
 	files *dagger.Directory,
+	// +optional
+	flatten bool,
+	// +optional
+	upsert bool,
+	// +optional
+	body string,
+	// +optional
+	draft bool,
+	// +optional
+	prerelease bool,
+	// +optional
+	makeLatest string,
+	// +optional
+	generateNotes bool,
+	// +optional
+	previousTag string,
+	// +optional
+	checksums bool,
+	// +optional
+	signingKey *dagger.Secret,
+	// +optional
+	signingKeyPassword *dagger.Secret,
+	// +optional
+	// +default="cosign"
+	signer string,
+	// +optional
+	// +default="Create new release"
+	tagMessage string,
+	// +optional
+	taggerName string,
+	// +optional
+	taggerEmail string,
 ) error {
 	client, err := m.getClient(ctx)
 	if err != nil {
 		return err
 	}
 
-	if name == "" {
+	// the name is only changed when upserting an existing release if it was set
+	update := &github.RepositoryRelease{}
+	if name != "" {
+		update.Name = &name
+	} else {
 		name = tag
 	}
 
-	rel, _, err := client.Repositories.CreateRelease(ctx, owner, repo, &github.RepositoryRelease{
-		Name:            &name,
-		TagName:         &tag,
-		TargetCommitish: &sha,
-	})
+	// if there are files to upload, export them before creating the release so that
+	// any problems with the assets are caught before anything is created on GitHub
+	var assets []releaseAsset
+	if files != nil {
+		dir, err := os.MkdirTemp("", "release-assets-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
 
-	if err != nil {
-		return fmt.Errorf("failed to create release: %w", err)
+		_, err = files.Export(ctx, dir)
+		if err != nil {
+			return fmt.Errorf("failed to export files: %w", err)
+		}
+
+		assets, err = collectReleaseAssets(dir, flatten)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(assets) > 0 && (checksums || signingKey != nil) {
+		// the manifest is written to the working directory so that it can be loaded into the signing container
+		dir, err := os.MkdirTemp(".", "release-checksums-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		extra, err := checksumAssets(ctx, dir, assets, signer, signingKey, signingKeyPassword)
+		if err != nil {
+			return err
+		}
+
+		assets = append(assets, extra...)
 	}
 
-	tagMessage := "Create new release"
-	_, _, err = client.Git.CreateTag(ctx, owner, repo, &github.Tag{
+	if generateNotes {
+		opts := &github.GenerateNotesOptions{
+			TagName:         tag,
+			TargetCommitish: &sha,
+		}
+
+		if previousTag != "" {
+			opts.PreviousTagName = &previousTag
+		}
+
+		notes, _, err := client.Repositories.GenerateReleaseNotes(ctx, owner, repo, opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate release notes: %w", classifyError(err))
+		}
+
+		if body != "" {
+			body = body + "\n\n" + notes.Body
+		} else {
+			body = notes.Body
+		}
+	}
+
+	release := &github.RepositoryRelease{
+		Name:       &name,
+		Body:       &body,
+		Draft:      &draft,
+		Prerelease: &prerelease,
+	}
+
+	if makeLatest != "" {
+		release.MakeLatest = &makeLatest
+	}
+
+	var rel *github.RepositoryRelease
+
+	// when upserting look for an existing release with the tag, if found the release is updated
+	// rather than failing on the duplicate
+	if upsert {
+		rel, err = findRelease(ctx, client, owner, repo, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rel != nil {
+		// only the fields that were set are changed so that an upsert does not publish a draft
+		// release or replace the notes of the existing release
+		if body != "" {
+			update.Body = &body
+		}
+
+		if draft {
+			update.Draft = &draft
+		}
+
+		if prerelease {
+			update.Prerelease = &prerelease
+		}
+
+		update.MakeLatest = release.MakeLatest
+
+		rel, _, err = client.Repositories.EditRelease(ctx, owner, repo, *rel.ID, update)
+
+		if err != nil {
+			return fmt.Errorf("failed to update release: %w", classifyError(err))
+		}
+
+		log.Debug("Updated release", "release", *rel.ID)
+	} else {
+		// create the annotated tag before the release, otherwise GitHub creates a lightweight tag for the release
+		err = m.createTag(ctx, client, owner, repo, tag, sha, tagMessage, taggerName, taggerEmail)
+		if err != nil {
+			return err
+		}
+
+		release.TagName = &tag
+		release.TargetCommitish = &sha
+
+		rel, _, err = client.Repositories.CreateRelease(ctx, owner, repo, release)
+
+		if err != nil {
+			return fmt.Errorf("failed to create release: %w", classifyError(err))
+		}
+
+		log.Debug("Created release", "release", *rel.ID)
+	}
+
+	if upsert {
+		return syncReleaseAssets(ctx, client, owner, repo, *rel.ID, assets)
+	}
+
+	for _, a := range assets {
+		err := uploadReleaseAsset(ctx, client, owner, repo, *rel.ID, a)
+		if err != nil {
+			return err
+		}
+
+		log.Debug("Added file to release", "file", a.Path, "name", a.Name)
+	}
+
+	return nil
+}
+
+// createTag creates an annotated tag object for the commit and the ref for the tag, the tag is signed when
+// WithGitSigning is set
+func (m *Github) createTag(ctx context.Context, client *github.Client, owner, repo, tag, sha, message, taggerName, taggerEmail string) error {
+	t := &github.Tag{
 		Tag:     &tag,
-		SHA:     &sha,
-		Message: &tagMessage,
+		Message: &message,
 		Object:  &github.GitObject{SHA: &sha, Type: github.String("commit")},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create tag: %w", err)
 	}
 
-	log.Debug("Created release", "release", *rel.ID)
+	if taggerName != "" || taggerEmail != "" {
+		t.Tagger = &github.CommitAuthor{Name: &taggerName, Email: &taggerEmail}
+	}
 
-	// if there are files to upload, upload them to the release
-	if files != nil {
-		assets := os.TempDir()
-		files.Export(ctx, assets)
+	if signer := m.gitSigner(ctx); signer != nil {
+		if taggerName == "" || taggerEmail == "" {
+			return fmt.Errorf("taggerName and taggerEmail must be set to create a signed tag")
+		}
+
+		// the tagger date is part of the signed payload
+		t.Tagger.Date = signingTime()
 
-		// get the files in the directory
-		fs, err := os.ReadDir(assets)
+		signed, err := signTag(signer, t)
 		if err != nil {
-			return fmt.Errorf("failed to read directory: %w", err)
+			return fmt.Errorf("failed to sign tag: %w", err)
 		}
 
-		for _, f := range fs {
-			if !f.IsDir() {
-				iof, err := os.OpenFile(path.Join(assets, f.Name()), os.O_RDONLY, 0600)
-				if err != nil {
-					return fmt.Errorf("failed to open file: %w", err)
-				}
+		t.Message = &signed
+	}
 
-				_, _, err = client.Repositories.UploadReleaseAsset(ctx, owner, repo, *rel.ID, &github.UploadOptions{Name: f.Name()}, iof)
-				if err != nil {
-					return fmt.Errorf("failed to upload file: %w", err)
-				}
+	to, _, err := client.Git.CreateTag(ctx, owner, repo, t)
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", classifyError(err))
+	}
 
-				log.Debug("Added file to release", "file", f.Name())
-			}
+	_, _, err = client.Git.CreateRef(ctx, owner, repo, &github.Reference{
+		Ref:    github.String("refs/tags/" + tag),
+		Object: &github.GitObject{SHA: to.SHA},
+	})
+
+	if isConflict(err) {
+		// the tag was pushed separately or created by a previous run that failed to create the release
+		return existingTag(ctx, client, owner, repo, tag, sha)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tag, classifyError(err))
+	}
+
+	log.Debug("Created tag", "tag", tag, "sha", sha, "signed", m.GitSigningKey != nil)
+
+	return nil
+}
+
+// existingTag checks that an existing tag points to the commit so that it can be used for the release,
+// the tag can be a lightweight tag or an annotated tag
+func existingTag(ctx context.Context, client *github.Client, owner, repo, tag, sha string) error {
+	ref, _, err := client.Git.GetRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil {
+		return fmt.Errorf("failed to get tag %s: %w", tag, classifyError(err))
+	}
+
+	target := ref.GetObject().GetSHA()
+
+	if ref.GetObject().GetType() == "tag" {
+		t, _, err := client.Git.GetTag(ctx, owner, repo, target)
+		if err != nil {
+			return fmt.Errorf("failed to get tag %s: %w", tag, classifyError(err))
 		}
+
+		target = t.GetObject().GetSHA()
 	}
 
+	if target != sha {
+		return fmt.Errorf("tag %s already exists for commit %s, expected commit %s: %w", tag, target, sha, ErrConflict)
+	}
+
+	log.Debug("Using existing tag", "tag", tag, "sha", sha)
+
 	return nil
 }
 
-// NextVersionFromAssociatedPRLabel returns a the next semantic version based on the presence of a PR label
-// for the given commit SHA.
-// If there are multiple PRs associated with the commit, the label from the latest PR will be used.
-//
-// i.e. if the SHA has an associated PR with a label of `major` and the current tag is `1.1.2` the next version will be `2.0.0`
-// if the PR has a tag of `minor` and the current tag is `1.1.2` the next version will be `1.2.0`
-// if the PR has a tag of `patch` and the current tag is `1.1.2` the next version will be `1.1.3`
-func (m *Github) NextVersionFromAssociatedPRLabel(
+// PublishRelease publishes a draft release for the given tag, this allows a release to be created as a draft
+// with CreateRelease and only made visible once all assets have been uploaded.
+func (m *Github) PublishRelease(
 	ctx context.Context,
 	owner,
 	repo,
-	sha string,
-) (string, error) {
+	tag string,
+	// +optional
+	makeLatest string,
+) error {
 	client, err := m.getClient(ctx)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	// find any associated PRs with the commit
-	prs := []*github.PullRequest{}
-	page := 0
+	rel, err := findRelease(ctx, client, owner, repo, tag)
+	if err != nil {
+		return err
+	}
 
-	// loop through and get all prs associated with the commit, list might be paged
-	for {
-		p, resp, err := client.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, &github.ListOptions{Page: page})
-		if err != nil {
-			return "", fmt.Errorf("failed to get pull requests: %w", err)
-		}
+	if rel == nil {
+		return fmt.Errorf("release for tag %s not found", tag)
+	}
 
-		prs = append(prs, p...)
+	release := &github.RepositoryRelease{
+		Draft: github.Bool(false),
+	}
 
-		if resp.NextPage == 0 {
-			break
-		}
+	if makeLatest != "" {
+		release.MakeLatest = &makeLatest
 	}
 
-	// no PRS associated with this commit, return an empty string
-	if len(prs) == 0 {
-		log.Debug("No PRs associated with commit")
-		return "", nil
+	_, _, err = client.Repositories.EditRelease(ctx, owner, repo, *rel.ID, release)
+	if err != nil {
+		return fmt.Errorf("failed to publish release: %w", classifyError(err))
+	}
+
+	log.Debug("Published release", "release", *rel.ID)
+
+	return nil
+}
+
+// findRelease returns the release for the given tag or nil when no release exists, draft releases
+// are not returned by the get release by tag API so the releases are listed when it is not found
+func findRelease(ctx context.Context, client *github.Client, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	rel, _, err := client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err == nil {
+		return rel, nil
+	}
+
+	if !isNotFound(err) {
+		return nil, fmt.Errorf("failed to get release: %w", classifyError(err))
 	}
 
-	versions := []*semver.Version{}
-	page = 0
+	page := 0
 
 	for {
-		// get the latest release tag
-		tags, resp, err := client.Repositories.ListTags(ctx, owner, repo, &github.ListOptions{Page: page})
+		rels, resp, err := client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{Page: page, PerPage: 100})
 		if err != nil {
-			return "", fmt.Errorf("failed to get releases: %w", err)
+			return nil, fmt.Errorf("failed to list releases: %w", classifyError(err))
 		}
 
-		for _, t := range tags {
-			// check if the tag is a semver, if so add it to the list
-			v, err := semver.NewVersion(*t.Name)
-			if err == nil {
-				versions = append(versions, v)
+		for _, r := range rels {
+			if r.GetTagName() == tag {
+				return r, nil
 			}
 		}
 
@@ -172,69 +459,85 @@ func (m *Github) NextVersionFromAssociatedPRLabel(
 		}
 	}
 
-	// sort the list of semver tags
-	sort.Sort(semver.Collection(versions))
-
-	// create the new tag
-	cv, _ := semver.NewVersion("v0.0.0")
-
-	// if there were any tags, get the latest one
-	if len(versions) > 0 {
-		cv = versions[len(versions)-1]
-	}
-
-	bump := ""
-	maxID := 0
-
-	// check the PRs for labels
-	for _, pr := range prs {
-		log.Debug("Checking PR for labels", "pr", *pr.Number, "labels", pr.Labels)
-
-		// only check the latest closed PR
-		if pr.Number != nil && *pr.Number > maxID {
-			// if there are multiple labels, get the highest one
-			lab := ""
-			for _, l := range pr.Labels {
-				switch *l.Name {
-				case "major":
-					lab = "major"
-				case "minor":
-					if lab != "major" {
-						lab = "minor"
-					}
-				case "patch":
-					if lab == "" {
-						lab = "patch"
-					}
-				}
-			}
+	return nil, nil
+}
 
-			maxID = *pr.Number
-			bump = lab
-		}
+// NextVersionFromAssociatedPRLabel returns a the next semantic version based on the presence of a PR label
+// for the given commit SHA.
+// If there are multiple PRs associated with the commit, the label from the latest PR will be used.
+//
+// i.e. if the SHA has an associated PR with a label of `major` and the current tag is `1.1.2` the next version will be `2.0.0`
+// if the PR has a tag of `minor` and the current tag is `1.1.2` the next version will be `1.2.0`
+// if the PR has a tag of `patch` and the current tag is `1.1.2` the next version will be `1.1.3`
+//
+// The label names can be changed with majorLabel, minorLabel and patchLabel. When tagPrefix is set only tags
+// starting with the prefix are considered, i.e. `vault/v` for the tag `vault/v1.2.0`, and the returned version
+// includes the prefix. If the PR has no version label defaultBump is used, when defaultBump is not set an
+// empty string is returned.
+//
+// If the PR has one of the prereleaseLabels a prerelease version is returned, the name of the prerelease is
+// the label after the last `:`, i.e. a PR with the labels `minor` and `semver:rc` and the current tag `1.1.2`
+// returns `1.2.0-rc.1`, if `1.2.0-rc.1` already exists `1.2.0-rc.2` is returned.
+// Versions are always incremented from the latest non-prerelease tag.
+func (m *Github) NextVersionFromAssociatedPRLabel(
+	ctx context.Context,
+	owner,
+	repo,
+	sha string,
+	// +optional
+	// +default="major"
+	majorLabel string,
+	// +optional
+	// +default="minor"
+	minorLabel string,
+	// +optional
+	// +default="patch"
+	patchLabel string,
+	// +optional
+	prereleaseLabels []string,
+	// +optional
+	defaultBump string,
+	// +optional
+	tagPrefix string,
+) (string, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// find any associated PRs with the commit
+	prs, err := listPullRequestsWithCommit(ctx, client, owner, repo, sha)
+	if err != nil {
+		return "", err
 	}
 
-	log.Debug("Setting version increment", "bump", bump)
+	// no PRS associated with this commit, return an empty string
+	if len(prs) == 0 {
+		log.Debug("No PRs associated with commit")
+		return "", nil
+	}
 
-	switch bump {
-	case "major":
-		nv := cv.IncMajor()
-		return nv.String(), nil
-	case "minor":
-		nv := cv.IncMinor()
-		return nv.String(), nil
-	case "patch":
-		nv := cv.IncPatch()
-		return nv.String(), nil
+	tags, err := listTags(ctx, client, owner, repo)
+	if err != nil {
+		return "", err
 	}
 
-	return "", nil
+	return nextVersion(prs, tagNames(tags), versionConfig{
+		MajorLabel:       majorLabel,
+		MinorLabel:       minorLabel,
+		PatchLabel:       patchLabel,
+		PrereleaseLabels: prereleaseLabels,
+		DefaultBump:      defaultBump,
+		TagPrefix:        tagPrefix,
+	})
 }
 
 // GetOIDCToken returns an OpenID Connect (OIDC) token for the current run in GitHubActions
 // When a actions run has the `id-token: write` permission, it can request an OIDC token for the current run
 // the parameters actionsRequestToken and actionsTokenURL are provided by the GitHubActions environment
 // variables `ACTIONS_ID_TOKEN_REQUEST_TOKEN` and `ACTIONS_ID_TOKEN_REQUEST_URL`.
+// The audience of the token defaults to the URL of the repository owner, cloud providers usually
+// require a specific audience such as `sts.amazonaws.com`.
 //
 // example actions config to enable OIDC tokens:
 // jobs:
@@ -244,13 +547,33 @@ func (m *Github) NextVersionFromAssociatedPRLabel(
 //	  permissions:
 //	    id-token: write
 //	    contents: read
-func (m *Github) GetOIDCToken(ctx context.Context, actionsRequestToken *dagger.Secret, actionsTokenURL string) (string, error) {
-	rq, err := http.NewRequest(http.MethodGet, actionsTokenURL, nil)
+func (m *Github) GetOIDCToken(
+	ctx context.Context,
+	actionsRequestToken *dagger.Secret,
+	actionsTokenURL string,
+	// +optional
+	audience string,
+) (*dagger.Secret, error) {
+	u, err := url.Parse(actionsTokenURL)
 	if err != nil {
-		return "", fmt.Errorf("unable to create request: %w", err)
+		return nil, fmt.Errorf("invalid token URL: %w", err)
 	}
 
-	tkn, _ := actionsRequestToken.Plaintext(ctx)
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	tkn, err := actionsRequestToken.Plaintext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read request token: %w", err)
+	}
 
 	// add the bearer token for the request
 	rq.Header.Add("Authorization", fmt.Sprintf("bearer %s", tkn))
@@ -258,28 +581,40 @@ func (m *Github) GetOIDCToken(ctx context.Context, actionsRequestToken *dagger.S
 	// make the request
 	resp, err := http.DefaultClient.Do(rq)
 	if err != nil {
-		return "", fmt.Errorf("unable to request token: %w", err)
+		return nil, fmt.Errorf("unable to request token: %w", err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("expected status 200, got %d", resp.StatusCode)
+		return nil, fmt.Errorf("expected status 200, got %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	// parse the response
-	data := map[string]interface{}{}
+	data := struct {
+		Value string `json:"value"`
+	}{}
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	err = json.Unmarshal(body, &data)
 	if err != nil {
-		return "", fmt.Errorf("unable to read response body: %w", err)
+		return nil, fmt.Errorf("unable to parse token response: %w", err)
+	}
+
+	if data.Value == "" {
+		return nil, fmt.Errorf("token response does not contain a value")
 	}
 
-	json.Unmarshal(body, &data)
-	gitHubJWT := data["value"].(string)
-	return gitHubJWT, nil
+	// secret names must be unique for each token
+	sum := sha256.Sum256([]byte(data.Value))
+
+	return dag.SetSecret(fmt.Sprintf("github-oidc-token-%x", sum[:8]), data.Value), nil
 }
 
-// CommitFile commits a file to a repository at the given path
+// CommitFile commits a file to a repository at the given path, when WithGitSigning is set the commit is signed
 func (m *Github) CommitFile(
 	ctx context.Context,
 	owner,
@@ -297,6 +632,31 @@ func (m *Github) CommitFile(
 		return "", err
 	}
 
+	// the contents API can not create signed commits, signed commits are created with the Git Data API
+	if m.GitSigningKey != nil {
+		dir, err := os.MkdirTemp("", "commit-file-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		_, err = file.Export(ctx, filepath.Join(dir, path.Base(commitPath)))
+		if err != nil {
+			return "", fmt.Errorf("failed to export file: %w", err)
+		}
+
+		return commitTree(ctx, c, commitOptions{
+			Owner:      owner,
+			Repo:       repo,
+			Branch:     branch,
+			Message:    message,
+			Author:     &github.CommitAuthor{Name: &commiterName, Email: &commiterEmail},
+			Root:       dir,
+			CommitPath: path.Dir(commitPath),
+			Signer:     m.gitSigner(ctx),
+		})
+	}
+
 	var commitBranch *string
 
 	if branch != "" {
@@ -317,8 +677,8 @@ func (m *Github) CommitFile(
 	f, _, _, err := c.Repositories.GetContents(ctx, owner, repo, commitPath, &github.RepositoryContentGetOptions{Ref: branch})
 	if err != nil {
 		// check if the error is a 404 error
-		if !strings.Contains(err.Error(), "404") {
-			return "", fmt.Errorf("failed to get file: %w", err)
+		if !isNotFound(err) {
+			return "", fmt.Errorf("failed to get file: %w", classifyError(err))
 		}
 	}
 
@@ -339,30 +699,89 @@ func (m *Github) CommitFile(
 		})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to update file: %w", err)
+		return "", fmt.Errorf("failed to update file: %w", classifyError(err))
 	}
 
 	return *cm.Commit.SHA, nil
 }
 
 func (m *Github) getClient(ctx context.Context) (*github.Client, error) {
-	if m.Token == nil {
-		log.Error("GitHub token not set")
-		return nil, fmt.Errorf("GitHub token not set, please use the WithToken function to set the token")
+	ts, err := m.tokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newClient(&http.Client{Transport: &oauth2.Transport{Source: ts, Base: newRetryTransport(nil)}})
+}
+
+// newClient creates a GitHub client for the configured API URLs that uses the given http client
+func (m *Github) newClient(hc *http.Client) (*github.Client, error) {
+	client := github.NewClient(hc)
+
+	if m.BaseURL != "" {
+		uploadURL := m.UploadURL
+		if uploadURL == "" {
+			uploadURL = m.BaseURL
+		}
+
+		var err error
+		client.BaseURL, err = parseAPIURL(m.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+
+		client.UploadURL, err = parseAPIURL(uploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload URL: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// parseAPIURL parses a GitHub API URL, the client requires the path to end with a trailing slash
+func parseAPIURL(u string) (*url.URL, error) {
+	if !strings.HasSuffix(u, "/") {
+		u = u + "/"
 	}
 
-	tkn, err := m.Token.Plaintext(ctx)
+	return url.Parse(u)
+}
+
+// graphql executes a query against the GitHub GraphQL API and decodes the data into out, the GraphQL API
+// is not under the REST API path for GitHub Enterprise Server so the path is resolved from the base URL
+func graphql(ctx context.Context, client *github.Client, query string, variables map[string]any, out any) error {
+	u := "graphql"
+	if strings.HasSuffix(client.BaseURL.Path, "/api/v3/") {
+		u = "../graphql"
+	}
+
+	req, err := client.NewRequest(http.MethodPost, u, map[string]any{"query": query, "variables": variables})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: tkn},
-	)
+	resp := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
 
-	tc := oauth2.NewClient(ctx, ts)
+	_, err = client.Do(ctx, req, &resp)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql error: %s", resp.Errors[0].Message)
+	}
+
+	if out != nil {
+		return json.Unmarshal(resp.Data, out)
+	}
 
-	return github.NewClient(tc), nil
+	return nil
 }
 
 // example: dagger call ftest-create-release --token=GITHUB_TOKEN --files=./testfiles
@@ -377,14 +796,14 @@ func (m *Github) FTestCreateRelease(
 
 	m.Token = token
 
-	v, err := m.NextVersionFromAssociatedPRLabel(ctx, "jumppad-labs", "daggerverse", "6976eb3f392256c384e87094853853f90c64ca68")
+	v, err := m.NextVersionFromAssociatedPRLabel(ctx, "jumppad-labs", "daggerverse", "6976eb3f392256c384e87094853853f90c64ca68", "major", "minor", "patch", nil, "", "")
 	if err != nil {
 		return err
 	}
 
 	log.Debug("new version", "version", v)
 
-	return m.CreateRelease(ctx, "jumppad-labs", "daggerverse", v, "6976eb3f392256c384e87094853853f90c64ca68", "", files)
+	return m.CreateRelease(ctx, "jumppad-labs", "daggerverse", v, "6976eb3f392256c384e87094853853f90c64ca68", "", files, false, false, "", false, false, "", false, "", false, nil, nil, "cosign", "Create new release", "", "")
 }
 
 // example: dagger call ftest-bump-version-with-prtag --token=GITHUB_TOKEN
@@ -394,7 +813,7 @@ func (m *Github) FTestBumpVersionWithPRTag(ctx context.Context, token *dagger.Se
 
 	m.Token = token
 
-	v, err := m.NextVersionFromAssociatedPRLabel(ctx, "jumppad-labs", "jumppad", "18e75c8517831bc29f5ce25528787c239ec670c1")
+	v, err := m.NextVersionFromAssociatedPRLabel(ctx, "jumppad-labs", "jumppad", "18e75c8517831bc29f5ce25528787c239ec670c1", "major", "minor", "patch", nil, "", "")
 	if err != nil {
 		return v, err
 	}
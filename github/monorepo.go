@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// ModuleVersion is the next version for a module in a monorepo
+type ModuleVersion struct {
+	// Module is the path of the module in the repository
+	Module string
+	// Tag is the latest tag for the module, empty when the module has not been released
+	Tag string
+	// Version is the next version for the module, empty when the changes do not require a release
+	Version string
+	// Commits are the SHAs of the commits that changed the module since the latest tag
+	Commits []string
+}
+
+// NextModuleVersions returns the next version for every module in a monorepo that has changed since its latest tag.
+// Only the commits that touch the module directory are considered, and the module is compared with the tags
+// starting with tagPrefix where `{module}` is replaced with the module path, i.e. `vault/v` for the tag
+// `vault/v1.2.0`. When modules is empty every top level directory of the repository is treated as a module.
+//
+// By default the version increment is taken from the labels of the PRs associated with the changed commits
+// in the same way as NextVersionFromAssociatedPRLabel, the highest label from any of the PRs is used.
+// When conventionalCommits is set the increment is calculated from the commit messages in the same way as
+// NextVersionFromConventionalCommits.
+func (m *Github) NextModuleVersions(
+	ctx context.Context,
+	owner,
+	repo,
+	sha string,
+	// +optional
+	modules []string,
+	// +optional
+	// +default="{module}/v"
+	tagPrefix string,
+	// +optional
+	conventionalCommits bool,
+	// +optional
+	// +default="major"
+	majorLabel string,
+	// +optional
+	// +default="minor"
+	minorLabel string,
+	// +optional
+	// +default="patch"
+	patchLabel string,
+	// +optional
+	prereleaseLabels []string,
+	// +optional
+	defaultBump string,
+) ([]*ModuleVersion, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(modules) == 0 {
+		modules, err = listModules(ctx, client, owner, repo, sha)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := listTags(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	mvs := []*ModuleVersion{}
+
+	for _, mod := range modules {
+		mod = strings.Trim(mod, "/")
+		cfg := versionConfig{
+			MajorLabel:       majorLabel,
+			MinorLabel:       minorLabel,
+			PatchLabel:       patchLabel,
+			PrereleaseLabels: prereleaseLabels,
+			DefaultBump:      defaultBump,
+			TagPrefix:        strings.ReplaceAll(tagPrefix, "{module}", mod),
+		}
+
+		mv, err := nextModuleVersion(ctx, client, owner, repo, sha, mod, tags, cfg, conventionalCommits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate version for %s: %w", mod, err)
+		}
+
+		if mv == nil {
+			log.Debug("Module has not changed", "module", mod)
+			continue
+		}
+
+		log.Debug("Module has changed", "module", mod, "tag", mv.Tag, "version", mv.Version, "commits", len(mv.Commits))
+
+		mvs = append(mvs, mv)
+	}
+
+	return mvs, nil
+}
+
+// nextModuleVersion returns the next version for a single module, nil is returned when the module has not changed
+func nextModuleVersion(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, sha, mod string,
+	tags []*github.RepositoryTag,
+	cfg versionConfig,
+	conventional bool,
+) (*ModuleVersion, error) {
+	versions := parseVersions(tagNames(tags), cfg.TagPrefix)
+	tag, cv := latestTag(versions, cfg.TagPrefix)
+
+	base := ""
+	for _, t := range tags {
+		if t.GetName() == tag {
+			base = t.GetCommit().GetSHA()
+		}
+	}
+
+	commits, err := listModuleCommits(ctx, client, owner, repo, base, sha, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	mv := &ModuleVersion{Module: mod, Tag: tag}
+	for _, c := range commits {
+		mv.Commits = append(mv.Commits, c.GetSHA())
+	}
+
+	bump := ""
+	channel := ""
+
+	if conventional {
+		_, bump = conventionalBump(commits)
+	} else {
+		maxID := 0
+		for _, c := range commits {
+			prs, err := listPullRequestsWithCommit(ctx, client, owner, repo, c.GetSHA())
+			if err != nil {
+				return nil, err
+			}
+
+			for _, pr := range prs {
+				b, ch := prLabels(pr, cfg)
+				if bumpRank(b) > bumpRank(bump) {
+					bump = b
+				}
+
+				// the prerelease channel is taken from the latest PR
+				if ch != "" && pr.GetNumber() > maxID {
+					maxID = pr.GetNumber()
+					channel = ch
+				}
+			}
+		}
+	}
+
+	if bump == "" {
+		bump = cfg.DefaultBump
+	}
+
+	// a prerelease label without a version label is a patch release
+	if bump == "" && channel != "" {
+		bump = "patch"
+	}
+
+	mv.Version, err = incrementVersion(cv, versions, bump, channel, cfg.TagPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return mv, nil
+}
+
+// listModuleCommits returns the commits reachable from sha and not from the base commit that change files in
+// the module directory, when base is empty all the commits that change the module are returned
+func listModuleCommits(ctx context.Context, client *github.Client, owner, repo, base, sha, mod string) ([]*github.RepositoryCommit, error) {
+	opts := &github.CommitsListOptions{
+		SHA:         sha,
+		Path:        mod,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	// the commits API can not list a range, remove any commits that are not in the comparison with the base.
+	// The commits are not filtered by date as merged or rebased commits can be older than the base commit
+	inRange := map[string]bool{}
+	if base != "" {
+		cs, err := listCommitsSince(ctx, client, owner, repo, base, sha)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range cs {
+			inRange[c.GetSHA()] = true
+		}
+	}
+
+	commits := []*github.RepositoryCommit{}
+
+	for {
+		cs, resp, err := client.Repositories.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list commits: %w", classifyError(err))
+		}
+
+		for _, c := range cs {
+			if base == "" || inRange[c.GetSHA()] {
+				commits = append(commits, c)
+			}
+		}
+
+		opts.Page = resp.NextPage
+		if opts.Page == 0 {
+			break
+		}
+	}
+
+	return commits, nil
+}
+
+// listModules returns the top level directories of the repository at the given ref
+func listModules(ctx context.Context, client *github.Client, owner, repo, ref string) ([]string, error) {
+	_, contents, _, err := client.Repositories.GetContents(ctx, owner, repo, "", &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository contents: %w", classifyError(err))
+	}
+
+	modules := []string{}
+	for _, c := range contents {
+		if c.GetType() == "dir" && !strings.HasPrefix(c.GetName(), ".") {
+			modules = append(modules, c.GetPath())
+		}
+	}
+
+	return modules, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"main/internal/dagger"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// clockSkew is the difference allowed between the clock of the issuer and the local clock
+// when checking the expiry of a token
+const clockSkew = 60 * time.Second
+
+// OIDCClaims are the verified claims of a GitHub Actions OIDC token
+type OIDCClaims struct {
+	Issuer   string
+	Subject  string
+	Audience []string
+	// Repository is the owner and name of the repository, i.e. `jumppad-labs/daggerverse`
+	Repository      string
+	RepositoryOwner string
+	// Ref is the git ref that triggered the workflow, i.e. `refs/heads/main`
+	Ref     string
+	RefType string
+	Sha     string
+	// Workflow is the name of the workflow
+	Workflow string
+	// WorkflowRef is the path and ref of the workflow file, i.e. `jumppad-labs/daggerverse/.github/workflows/build.yaml@refs/heads/main`
+	WorkflowRef string
+	// JobWorkflowRef is the path and ref of the reusable workflow that ran the job
+	JobWorkflowRef string
+	Environment    string
+	EventName      string
+	Actor          string
+	RunID          string
+	RunAttempt     string
+	// ExpiresAt is the unix time the token expires
+	ExpiresAt int
+}
+
+// oidcClaims is the payload of a GitHub Actions OIDC token
+type oidcClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	ExpiresAt       int64    `json:"exp"`
+	NotBefore       int64    `json:"nbf"`
+	Repository      string   `json:"repository"`
+	RepositoryOwner string   `json:"repository_owner"`
+	Ref             string   `json:"ref"`
+	RefType         string   `json:"ref_type"`
+	Sha             string   `json:"sha"`
+	Workflow        string   `json:"workflow"`
+	WorkflowRef     string   `json:"workflow_ref"`
+	JobWorkflowRef  string   `json:"job_workflow_ref"`
+	Environment     string   `json:"environment"`
+	EventName       string   `json:"event_name"`
+	Actor           string   `json:"actor"`
+	RunID           string   `json:"run_id"`
+	RunAttempt      string   `json:"run_attempt"`
+}
+
+// audience is the aud claim, a token can have a single audience or a list
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// jwks is a JSON Web Key Set containing the keys used to sign tokens
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// DecodeOIDCClaims verifies a GitHub Actions OIDC token and returns its claims. The signature is checked against
+// the jwks file when set, otherwise the keys are fetched from the issuer. The token must be issued by the issuer,
+// not be expired and, when audience is set, be issued for the audience.
+// For GitHub Enterprise Server the issuer is `https://HOSTNAME/_services/token`.
+func (m *Github) DecodeOIDCClaims(
+	ctx context.Context,
+	token *dagger.Secret,
+	// +optional
+	jwks *dagger.File,
+	// +optional
+	// +default="https://token.actions.githubusercontent.com"
+	issuer string,
+	// +optional
+	audience string,
+) (*OIDCClaims, error) {
+	tkn, err := token.Plaintext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token: %w", err)
+	}
+
+	var keys []byte
+	if jwks != nil {
+		c, err := jwks.Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read JWKS: %w", err)
+		}
+
+		keys = []byte(c)
+	} else {
+		keys, err = fetchJWKS(ctx, issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	claims, err := verifyOIDCToken(tkn, keys, issuer, audience, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("Verified OIDC token", "repository", claims.Repository, "ref", claims.Ref, "sha", claims.Sha)
+
+	return &OIDCClaims{
+		Issuer:          claims.Issuer,
+		Subject:         claims.Subject,
+		Audience:        claims.Audience,
+		Repository:      claims.Repository,
+		RepositoryOwner: claims.RepositoryOwner,
+		Ref:             claims.Ref,
+		RefType:         claims.RefType,
+		Sha:             claims.Sha,
+		Workflow:        claims.Workflow,
+		WorkflowRef:     claims.WorkflowRef,
+		JobWorkflowRef:  claims.JobWorkflowRef,
+		Environment:     claims.Environment,
+		EventName:       claims.EventName,
+		Actor:           claims.Actor,
+		RunID:           claims.RunID,
+		RunAttempt:      claims.RunAttempt,
+		ExpiresAt:       int(claims.ExpiresAt),
+	}, nil
+}
+
+// verifyOIDCToken checks the signature of the token with the keys in the JWKS and validates the
+// issuer, audience and expiry of the token
+func verifyOIDCToken(token string, keySet []byte, issuer, aud string, now time.Time) (*oidcClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid token, expected a JWT")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %q, expected RS256", header.Alg)
+	}
+
+	key, err := findKey(keySet, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	claims := &oidcClaims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	if claims.Issuer != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("token was issued by %q, expected %q", claims.Issuer, issuer)
+	}
+
+	if aud != "" && !slices.Contains(claims.Audience, aud) {
+		return nil, fmt.Errorf("token audience %v does not contain %q", []string(claims.Audience), aud)
+	}
+
+	if claims.ExpiresAt == 0 || now.Add(-clockSkew).After(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, fmt.Errorf("token has expired")
+	}
+
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+
+	return claims, nil
+}
+
+// decodeSegment decodes a base64 encoded JSON segment of a JWT
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// findKey returns the RSA public key with the given id from the JWKS
+func findKey(keySet []byte, kid string) (*rsa.PublicKey, error) {
+	set := jwks{}
+	if err := json.Unmarshal(keySet, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (kid != "" && k.Kid != kid) {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q: %w", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q: %w", k.Kid, err)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+
+	return nil, fmt.Errorf("no RSA key found in JWKS for key id %q", kid)
+}
+
+// fetchJWKS fetches the signing keys of the issuer using OpenID Connect discovery
+func fetchJWKS(ctx context.Context, issuer string) ([]byte, error) {
+	config := struct {
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+
+	data, err := getURL(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch OpenID configuration: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil || config.JWKSURI == "" {
+		return nil, fmt.Errorf("invalid OpenID configuration for issuer %s", issuer)
+	}
+
+	data, err = getURL(ctx, config.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch JWKS: %w", err)
+	}
+
+	return data, nil
+}
+
+// getURL returns the body of a GET request to the URL
+func getURL(ctx context.Context, u string) ([]byte, error) {
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(rq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
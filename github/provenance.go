@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"main/internal/dagger"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// statementType is the type of an in-toto v1 statement
+	statementType = "https://in-toto.io/Statement/v1"
+
+	// provenanceType is the predicate type of SLSA v1 provenance
+	provenanceType = "https://slsa.dev/provenance/v1"
+
+	// dssePayloadType is the payload type of a DSSE envelope containing an in-toto statement
+	dssePayloadType = "application/vnd.in-toto+json"
+)
+
+// statement is an in-toto statement, see https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md
+type statement struct {
+	Type          string               `json:"_type"`
+	Subject       []resourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     provenance           `json:"predicate"`
+}
+
+// resourceDescriptor describes an artifact or source by its name or URI and digest
+type resourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// provenance is the SLSA v1 provenance predicate, see https://slsa.dev/spec/v1.0/provenance
+type provenance struct {
+	BuildDefinition struct {
+		BuildType            string               `json:"buildType"`
+		ExternalParameters   map[string]any       `json:"externalParameters"`
+		ResolvedDependencies []resourceDescriptor `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			InvocationID string `json:"invocationId,omitempty"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+// dsseEnvelope is a signed DSSE envelope, see https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// CreateProvenance creates a SLSA v1 provenance statement for the files built from the given commit and returns it
+// as a signed in-toto `.intoto.jsonl` file. The subjects of the statement are named in the same way as the assets
+// uploaded by CreateRelease, set flatten to match the assets of a release created with flatten.
+//
+// The statement is wrapped in a DSSE envelope signed with the cosign signingKey, signingKeyPassword is the password
+// for encrypted keys. Parameters are recorded as the external parameters of the build and are in the form
+// `name=value`. When tag is set the source is recorded as the tag and the file is attached to the release for the tag.
+func (m *Github) CreateProvenance(
+	ctx context.Context,
+	owner,
+	repo,
+	sha string,
+	files *dagger.Directory,
+	signingKey *dagger.Secret,
+	// +optional
+	signingKeyPassword *dagger.Secret,
+	// +optional
+	flatten bool,
+	// +optional
+	tag string,
+	// +optional
+	// +default="https://github.com/jumppad-labs/daggerverse/github"
+	builderID string,
+	// +optional
+	// +default="https://github.com/jumppad-labs/daggerverse/github/buildtypes/dagger@v1"
+	buildType string,
+	// +optional
+	parameters []string,
+	// +optional
+	invocationID string,
+	// +optional
+	// +default="provenance.intoto.jsonl"
+	name string,
+) (*dagger.File, error) {
+	if !strings.HasSuffix(name, ".intoto.jsonl") {
+		return nil, fmt.Errorf("invalid name %q, the name must end with .intoto.jsonl", name)
+	}
+
+	params := map[string]any{}
+	for _, p := range parameters {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter %q, parameters must be in the form name=value", p)
+		}
+
+		params[k] = v
+	}
+
+	// files are exported to calculate the digests of the subjects
+	exported, err := os.MkdirTemp("", "provenance-subjects-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(exported)
+
+	_, err = files.Export(ctx, exported)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export files: %w", err)
+	}
+
+	assets, err := collectReleaseAssets(exported, flatten)
+	if err != nil {
+		return nil, err
+	}
+
+	// a statement must have at least one subject
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("no files found, provenance must have at least one subject")
+	}
+
+	st := statement{
+		Type:          statementType,
+		PredicateType: provenanceType,
+	}
+
+	for _, a := range assets {
+		sum, err := fileChecksum(a.Location)
+		if err != nil {
+			return nil, err
+		}
+
+		st.Subject = append(st.Subject, resourceDescriptor{Name: a.Name, Digest: map[string]string{"sha256": sum}})
+	}
+
+	source := fmt.Sprintf("git+%s/%s/%s", m.serverURL(), owner, repo)
+
+	ref := source + "@" + sha
+	if tag != "" {
+		ref = source + "@refs/tags/" + tag
+	}
+
+	params["source"] = ref
+
+	st.Predicate.BuildDefinition.BuildType = buildType
+	st.Predicate.BuildDefinition.ExternalParameters = params
+	st.Predicate.BuildDefinition.ResolvedDependencies = []resourceDescriptor{{URI: ref, Digest: map[string]string{"gitCommit": sha}}}
+	st.Predicate.RunDetails.Builder.ID = builderID
+	st.Predicate.RunDetails.Metadata.InvocationID = invocationID
+
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance: %w", err)
+	}
+
+	// the statement and signature are written to the working directory so they can be loaded by dagger
+	dir, err := os.MkdirTemp(".", "provenance-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	sig, err := signDSSE(ctx, dir, payload, signingKey, signingKeyPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := json.Marshal(dsseEnvelope{
+		PayloadType: dssePayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []dsseSignature{{Sig: sig}},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode envelope: %w", err)
+	}
+
+	location := filepath.Join(dir, name)
+	err = os.WriteFile(location, append(envelope, '\n'), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	log.Debug("Created provenance", "name", name, "subjects", len(st.Subject), "sha", sha)
+
+	if tag != "" {
+		client, err := m.getClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := findRelease(ctx, client, owner, repo, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if rel == nil {
+			return nil, fmt.Errorf("release for tag %s not found", tag)
+		}
+
+		// replace any provenance from a previous run of the release
+		err = syncReleaseAssets(ctx, client, owner, repo, rel.GetID(), []releaseAsset{{
+			Name:        name,
+			Path:        name,
+			Location:    location,
+			ContentType: "application/jsonl",
+		}})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d, err := dag.CurrentModule().Workdir(dir).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.File(name), nil
+}
+
+// signDSSE signs the DSSE pre-authentication encoding of the payload with cosign and returns the base64
+// encoded signature, the encoding is written to dir so that it can be loaded into the signing container
+func signDSSE(ctx context.Context, dir string, payload []byte, key, password *dagger.Secret) (string, error) {
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(dssePayloadType), dssePayloadType, len(payload), payload)
+
+	err := os.WriteFile(filepath.Join(dir, "statement.pae"), []byte(pae), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	file := dag.CurrentModule().Workdir(dir).File("statement.pae")
+
+	sig, _, err := signBlob(ctx, "cosign", "statement.pae", file, key, password)
+	if err != nil {
+		return "", err
+	}
+
+	// cosign writes the signature base64 encoded
+	s, err := sig.Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	return strings.TrimSpace(s), nil
+}
+
+// serverURL returns the URL of the GitHub server that hosts the repositories
+func (m *Github) serverURL() string {
+	if strings.HasSuffix(strings.TrimSuffix(m.BaseURL, "/"), "/api/v3") {
+		return strings.TrimSuffix(strings.TrimSuffix(m.BaseURL, "/"), "/api/v3")
+	}
+
+	return "https://github.com"
+}
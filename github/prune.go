@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// PruneReleases deletes prereleases and draft releases that are no longer needed and returns the tags of the
+// releases that were deleted. Releases are deleted when they are older than olderThanDays, or when they are
+// not one of the last keepLast releases in their major or minor version line, keepPer is either `major` or
+// `minor`. When both are set a release is only deleted when it is older than olderThanDays and not one of
+// the last keepLast releases, releases with tags that are not semantic versions are only pruned by age.
+//
+// When tagPrefix is set only releases with tags starting with the prefix are pruned, i.e. `vault/v`. Set
+// includeReleases to also prune published releases, the latest release is never deleted. The git tags of
+// deleted releases are kept unless deleteTags is set. With dryRun the releases that would be deleted are
+// returned without making any changes.
+func (m *Github) PruneReleases(
+	ctx context.Context,
+	owner,
+	repo string,
+	// +optional
+	olderThanDays int,
+	// +optional
+	keepLast int,
+	// +optional
+	// +default="minor"
+	keepPer string,
+	// +optional
+	tagPrefix string,
+	// +optional
+	includeReleases bool,
+	// +optional
+	deleteTags bool,
+	// +optional
+	dryRun bool,
+) ([]string, error) {
+	if olderThanDays < 0 || keepLast < 0 {
+		return nil, fmt.Errorf("olderThanDays and keepLast must not be negative")
+	}
+
+	if olderThanDays == 0 && keepLast == 0 {
+		return nil, fmt.Errorf("olderThanDays or keepLast must be set")
+	}
+
+	if keepPer != "major" && keepPer != "minor" {
+		return nil, fmt.Errorf("invalid keepPer %q, expected major or minor", keepPer)
+	}
+
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := listReleases(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int64
+	if includeReleases {
+		rel, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
+		if err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("failed to get latest release: %w", classifyError(err))
+		}
+
+		latest = rel.GetID()
+	}
+
+	candidates := []*github.RepositoryRelease{}
+	for _, r := range releases {
+		if !strings.HasPrefix(r.GetTagName(), tagPrefix) || r.GetID() == latest {
+			continue
+		}
+
+		if includeReleases || r.GetPrerelease() || r.GetDraft() {
+			candidates = append(candidates, r)
+		}
+	}
+
+	kept := map[int64]bool{}
+	if keepLast > 0 {
+		kept = lastReleases(candidates, tagPrefix, keepLast, keepPer)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -olderThanDays)
+	pruned := []string{}
+
+	for _, r := range candidates {
+		if olderThanDays > 0 && !r.GetCreatedAt().Before(cutoff) {
+			continue
+		}
+
+		if keepLast > 0 && kept[r.GetID()] {
+			continue
+		}
+
+		// releases without a semantic version can not be grouped so are only pruned by age
+		if olderThanDays == 0 && !isVersion(r.GetTagName(), tagPrefix) {
+			continue
+		}
+
+		pruned = append(pruned, r.GetTagName())
+
+		if dryRun {
+			log.Info("Would delete release", "tag", r.GetTagName(), "draft", r.GetDraft(), "prerelease", r.GetPrerelease(), "created", r.GetCreatedAt())
+			continue
+		}
+
+		_, err := client.Repositories.DeleteRelease(ctx, owner, repo, r.GetID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete release %s: %w", r.GetTagName(), classifyError(err))
+		}
+
+		log.Debug("Deleted release", "tag", r.GetTagName(), "release", r.GetID())
+
+		if !deleteTags {
+			continue
+		}
+
+		// draft releases only have a tag when it was created before the release
+		_, err = client.Git.DeleteRef(ctx, owner, repo, "tags/"+r.GetTagName())
+		if err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("failed to delete tag %s: %w", r.GetTagName(), classifyError(err))
+		}
+
+		log.Debug("Deleted tag", "tag", r.GetTagName())
+	}
+
+	return pruned, nil
+}
+
+// lastReleases returns the ids of the newest keep releases for each major or minor version line
+func lastReleases(releases []*github.RepositoryRelease, prefix string, keep int, per string) map[int64]bool {
+	type versioned struct {
+		id      int64
+		version *semver.Version
+	}
+
+	lines := map[string][]versioned{}
+
+	for _, r := range releases {
+		v, err := semver.NewVersion(strings.TrimPrefix(r.GetTagName(), prefix))
+		if err != nil {
+			continue
+		}
+
+		line := fmt.Sprintf("%d", v.Major())
+		if per == "minor" {
+			line = fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+		}
+
+		lines[line] = append(lines[line], versioned{id: r.GetID(), version: v})
+	}
+
+	kept := map[int64]bool{}
+
+	for _, vs := range lines {
+		sort.Slice(vs, func(i, j int) bool { return vs[i].version.GreaterThan(vs[j].version) })
+
+		for i := 0; i < len(vs) && i < keep; i++ {
+			kept[vs[i].id] = true
+		}
+	}
+
+	return kept
+}
+
+// isVersion returns true when the tag is a semantic version after removing the prefix
+func isVersion(tag, prefix string) bool {
+	_, err := semver.NewVersion(strings.TrimPrefix(tag, prefix))
+	return err == nil
+}
+
+// listReleases returns all the releases in the repository including drafts
+func listReleases(ctx context.Context, client *github.Client, owner, repo string) ([]*github.RepositoryRelease, error) {
+	releases := []*github.RepositoryRelease{}
+	page := 0
+
+	for {
+		rels, resp, err := client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", classifyError(err))
+		}
+
+		releases = append(releases, rels...)
+
+		if resp.NextPage == 0 {
+			return releases, nil
+		}
+
+		page = resp.NextPage
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"main/internal/dagger"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// CreatePullRequest commits a file or the contents of a directory to the head branch and opens a pull request
+// against the base branch, this allows changes to be made to repositories that have branch protection.
+// The head branch is created from the base branch if it does not exist. If there is already an open pull request
+// for the head branch it is updated rather than a new pull request being created.
+//
+// Reviewers in the form `org/team` are requested as team reviewers. When autoMerge is set, auto-merge is enabled
+// for the pull request using mergeMethod, one of `merge`, `squash` or `rebase`.
+// Returns the number of the pull request.
+func (m *Github) CreatePullRequest(
+	ctx context.Context,
+	owner,
+	repo,
+	commiterName,
+	commiterEmail,
+	message,
+	head,
+	title string,
+	// +optional
+	file *dagger.File,
+	// +optional
+	directory *dagger.Directory,
+	// +optional
+	commitPath string,
+	// +optional
+	base string,
+	// +optional
+	body string,
+	// +optional
+	labels []string,
+	// +optional
+	reviewers []string,
+	// +optional
+	assignees []string,
+	// +optional
+	autoMerge bool,
+	// +optional
+	// +default="squash"
+	mergeMethod string,
+) (int, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if base == "" {
+		r, _, err := client.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get repository: %w", classifyError(err))
+		}
+
+		base = r.GetDefaultBranch()
+	}
+
+	if file != nil || directory != nil {
+		dir, err := os.MkdirTemp("", "pull-request-*")
+		if err != nil {
+			return 0, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		opts := commitOptions{
+			Owner:   owner,
+			Repo:    repo,
+			Branch:  head,
+			BaseRef: base,
+			Message: message,
+			Author:  &github.CommitAuthor{Name: &commiterName, Email: &commiterEmail},
+			Root:    dir,
+			Signer:  m.gitSigner(ctx),
+		}
+
+		// a single file is exported to its path in the repository, directories are exported to the root
+		// and committed at the given path
+		if file != nil {
+			if commitPath == "" {
+				return 0, fmt.Errorf("commitPath must be set when committing a file")
+			}
+
+			_, err = file.Export(ctx, filepath.Join(dir, filepath.FromSlash(path.Clean(commitPath))))
+		} else {
+			opts.CommitPath = commitPath
+			_, err = directory.Export(ctx, dir)
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to export files: %w", err)
+		}
+
+		_, err = commitTree(ctx, client, opts)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	pr, err := upsertPullRequest(ctx, client, owner, repo, head, base, title, body)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(labels) > 0 {
+		_, _, err = client.Issues.AddLabelsToIssue(ctx, owner, repo, pr.GetNumber(), labels)
+		if err != nil {
+			return 0, fmt.Errorf("failed to add labels: %w", classifyError(err))
+		}
+	}
+
+	if len(reviewers) > 0 {
+		rr := github.ReviewersRequest{}
+		for _, r := range reviewers {
+			if _, team, ok := strings.Cut(r, "/"); ok {
+				rr.TeamReviewers = append(rr.TeamReviewers, team)
+			} else {
+				rr.Reviewers = append(rr.Reviewers, r)
+			}
+		}
+
+		_, _, err = client.PullRequests.RequestReviewers(ctx, owner, repo, pr.GetNumber(), rr)
+		if err != nil {
+			return 0, fmt.Errorf("failed to request reviewers: %w", classifyError(err))
+		}
+	}
+
+	if len(assignees) > 0 {
+		_, _, err = client.Issues.AddAssignees(ctx, owner, repo, pr.GetNumber(), assignees)
+		if err != nil {
+			return 0, fmt.Errorf("failed to add assignees: %w", classifyError(err))
+		}
+	}
+
+	if autoMerge {
+		err = enableAutoMerge(ctx, client, pr.GetNodeID(), mergeMethod)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return pr.GetNumber(), nil
+}
+
+// upsertPullRequest updates the open pull request for the head branch or creates a new one
+func upsertPullRequest(ctx context.Context, client *github.Client, owner, repo, head, base, title, body string) (*github.PullRequest, error) {
+	prs, _, err := client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  owner + ":" + head,
+		Base:  base,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", classifyError(err))
+	}
+
+	if len(prs) > 0 {
+		pr, _, err := client.PullRequests.Edit(ctx, owner, repo, prs[0].GetNumber(), &github.PullRequest{
+			Title: &title,
+			Body:  &body,
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to update pull request: %w", classifyError(err))
+		}
+
+		log.Debug("Updated pull request", "pr", pr.GetNumber())
+
+		return pr, nil
+	}
+
+	pr, _, err := client.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", classifyError(err))
+	}
+
+	log.Debug("Created pull request", "pr", pr.GetNumber())
+
+	return pr, nil
+}
+
+// enableAutoMerge enables auto-merge for a pull request, this is only available with the GraphQL API
+func enableAutoMerge(ctx context.Context, client *github.Client, nodeID, mergeMethod string) error {
+	query := `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) {
+    clientMutationId
+  }
+}`
+
+	err := graphql(ctx, client, query, map[string]any{
+		"id":     nodeID,
+		"method": strings.ToUpper(mergeMethod),
+	}, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to enable auto-merge: %w", classifyError(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"main/internal/dagger"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// checksumsName is the name of the checksum manifest uploaded with the release assets
+const checksumsName = "SHA256SUMS"
+
+// checksumAssets writes a SHA256SUMS manifest covering the assets to dir, when a signing key is set a detached
+// signature of the manifest is created with signer. Returns the manifest and signature as release assets.
+// The directory must be in the module working directory so that the manifest can be loaded for signing.
+func checksumAssets(ctx context.Context, dir string, assets []releaseAsset, signer string, key, password *dagger.Secret) ([]releaseAsset, error) {
+	// the generated files must not replace a file with the same name
+	generated := map[string]string{checksumsName: "checksums"}
+	if key != nil {
+		sigName, err := signatureName(signer, checksumsName)
+		if err != nil {
+			return nil, err
+		}
+
+		generated[sigName] = "signature"
+	}
+
+	for _, a := range assets {
+		if kind, ok := generated[a.Name]; ok {
+			return nil, fmt.Errorf("file %q and the generated %s would both be uploaded as %q", a.Path, kind, a.Name)
+		}
+	}
+
+	sb := strings.Builder{}
+
+	for _, a := range assets {
+		sum, err := fileChecksum(a.Location)
+		if err != nil {
+			return nil, err
+		}
+
+		// the same format as sha256sum so the file can be checked with `sha256sum -c`
+		sb.WriteString(fmt.Sprintf("%s  %s\n", sum, a.Name))
+	}
+
+	err := os.WriteFile(filepath.Join(dir, checksumsName), []byte(sb.String()), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", checksumsName, err)
+	}
+
+	extra := []releaseAsset{{
+		Name:        checksumsName,
+		Path:        checksumsName,
+		Location:    filepath.Join(dir, checksumsName),
+		ContentType: "text/plain; charset=utf-8",
+	}}
+
+	log.Debug("Created checksums", "name", checksumsName, "assets", len(assets))
+
+	if key == nil {
+		return extra, nil
+	}
+
+	manifest := dag.CurrentModule().Workdir(dir).File(checksumsName)
+
+	sig, sigName, err := signBlob(ctx, signer, checksumsName, manifest, key, password)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = sig.Export(ctx, filepath.Join(dir, sigName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export signature: %w", err)
+	}
+
+	log.Debug("Signed checksums", "name", sigName, "signer", signer)
+
+	return append(extra, releaseAsset{
+		Name:        sigName,
+		Path:        sigName,
+		Location:    filepath.Join(dir, sigName),
+		ContentType: "application/octet-stream",
+	}), nil
+}
+
+// signBlob creates a detached signature for the file using cosign or minisign and returns the signature
+// and its file name. The key is the private key for the signer and password the optional password
+// used to encrypt the key.
+func signBlob(ctx context.Context, signer, name string, file *dagger.File, key, password *dagger.Secret) (*dagger.File, string, error) {
+	sigName, err := signatureName(signer, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var ctr *dagger.Container
+
+	switch signer {
+	case "cosign":
+		ctr = dag.Container().
+			From("cgr.dev/chainguard/cosign:latest").
+			WithMountedFile("/work/"+name, file).
+			WithMountedSecret("/keys/cosign.key", key)
+
+		// cosign reads the password of the key from the environment, an empty password is used for unencrypted keys
+		if password != nil {
+			ctr = ctr.WithSecretVariable("COSIGN_PASSWORD", password)
+		} else {
+			ctr = ctr.WithEnvVariable("COSIGN_PASSWORD", "")
+		}
+
+		ctr = ctr.WithExec([]string{
+			"cosign", "sign-blob",
+			"--yes",
+			"--tlog-upload=false",
+			"--key", "/keys/cosign.key",
+			"--output-signature", "/tmp/" + sigName,
+			"/work/" + name,
+		})
+
+	case "minisign":
+		ctr = dag.Container().
+			From("alpine:latest").
+			WithExec([]string{"apk", "add", "--no-cache", "minisign"}).
+			WithMountedFile("/work/"+name, file).
+			WithMountedSecret("/keys/minisign.key", key)
+
+		if password != nil {
+			ctr = ctr.WithSecretVariable("MINISIGN_PASSWORD", password)
+		}
+
+		// minisign reads the password from stdin when it is not a terminal, the variable is unset for
+		// unencrypted keys which ignore the input
+		ctr = ctr.WithExec([]string{
+			"sh", "-c",
+			fmt.Sprintf(`printf '%%s\n' "$MINISIGN_PASSWORD" | minisign -S -s /keys/minisign.key -m /work/%s -x /tmp/%s`, name, sigName),
+		})
+	}
+
+	sig, err := ctr.File("/tmp/" + sigName).Sync(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign %s: %w", name, err)
+	}
+
+	return sig, sigName, nil
+}
+
+// signatureName returns the name of the detached signature created by the signer for the file
+func signatureName(signer, name string) (string, error) {
+	switch signer {
+	case "cosign":
+		return name + ".sig", nil
+	case "minisign":
+		return name + ".minisig", nil
+	}
+
+	return "", fmt.Errorf("unsupported signer %q, expected cosign or minisign", signer)
+}
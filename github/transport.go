@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRetries is the number of times a request is retried before the last response is returned
+	maxRetries = 5
+
+	// maxRetryWait caps the time waited before a single retry, longer rate limit resets are not waited for
+	maxRetryWait = 2 * time.Minute
+
+	// maxCachedResponses bounds the number of responses kept for conditional requests
+	maxCachedResponses = 500
+)
+
+// cache holds the responses for conditional requests, it is shared between clients
+// so that repeated reads across function calls can be answered with a 304
+var cache = &etagCache{entries: map[string]*cachedResponse{}}
+
+// retryTransport is a http.RoundTripper that retries rate limited and failed requests
+// and uses ETags to make conditional requests for repeated reads
+type retryTransport struct {
+	base  http.RoundTripper
+	cache *etagCache
+
+	// sleep waits for the given duration, it returns early when the request is cancelled
+	sleep func(req *http.Request, d time.Duration) error
+}
+
+// newRetryTransport creates a retryTransport that wraps base
+func newRetryTransport(base http.RoundTripper) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &retryTransport{base: base, cache: cache, sleep: sleepContext}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+	cached := t.cache.get(key)
+
+	for attempt := 0; ; attempt++ {
+		r, err := t.prepare(req, attempt, cached)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(r)
+
+		wait, retry := retryAfter(req, resp, err, attempt)
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
+
+			return t.cache.handle(key, cached, resp)
+		}
+
+		// drain the body so that the connection can be reused
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := t.sleep(req, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// prepare clones the request for an attempt, the body is replayed for retries and
+// the ETag of a cached response is sent so that GitHub can reply with a 304
+func (t *retryTransport) prepare(req *http.Request, attempt int, cached *cachedResponse) (*http.Request, error) {
+	r := req.Clone(req.Context())
+
+	if attempt > 0 && req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+
+		r.Body = body
+	}
+
+	if cached != nil && r.Header.Get("If-None-Match") == "" {
+		r.Header.Set("If-None-Match", cached.etag)
+	}
+
+	return r, nil
+}
+
+// retryAfter returns how long to wait before retrying the request and if it should be retried at all,
+// rate limited requests are retried for any replayable request, server and network errors only for
+// idempotent requests as GitHub may have applied the change before failing
+func retryAfter(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= maxRetries || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	replayable := req.Body == nil || req.GetBody != nil
+
+	if err != nil {
+		return backoff(attempt), idempotent(req) && replayable
+	}
+
+	if d, ok := rateLimitWait(resp); ok {
+		return d, replayable && d <= maxRetryWait
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return backoff(attempt), idempotent(req) && replayable
+	}
+
+	return 0, false
+}
+
+// rateLimitWait returns how long GitHub asked the client to wait when a request was rate limited,
+// secondary rate limits set Retry-After and primary rate limits set the time the quota resets
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if s, err := strconv.Atoi(v); err == nil {
+			return time.Duration(s) * time.Second, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if s, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			d := time.Until(time.Unix(s, 0))
+			if d < 0 {
+				d = 0
+			}
+
+			return d, true
+		}
+	}
+
+	// a 429 without any headers is still a rate limit
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return time.Minute, true
+	}
+
+	return 0, false
+}
+
+// backoff returns the jittered exponential backoff for the attempt, starting at one second
+func backoff(attempt int) time.Duration {
+	d := time.Second << attempt
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// idempotent returns true when the request can be safely repeated
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// sleepContext waits for the duration or until the request is cancelled
+func sleepContext(req *http.Request, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// cachedResponse is a successful response to a GET request that returned an ETag
+type cachedResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// etagCache stores responses keyed by the request, when full the cache is reset
+type etagCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+// cacheKey returns the key for the request or an empty string when the request can not be cached,
+// the credentials are part of the key so that responses are never shared between users
+func cacheKey(req *http.Request) string {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return ""
+	}
+
+	return req.URL.String() + "\n" + req.Header.Get("Accept") + "\n" + req.Header.Get("Authorization")
+}
+
+func (c *etagCache) get(key string) *cachedResponse {
+	if key == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.entries[key]
+}
+
+func (c *etagCache) set(key string, cr *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedResponses {
+		c.entries = map[string]*cachedResponse{}
+	}
+
+	c.entries[key] = cr
+}
+
+// handle replaces a 304 with the cached response and stores new responses that have an ETag
+func (c *etagCache) handle(key string, cached *cachedResponse, resp *http.Response) (*http.Response, error) {
+	if key == "" {
+		return resp, nil
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+
+		header := cached.header.Clone()
+		// keep the fresh rate limit headers from the 304
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(cached.body))
+		resp.ContentLength = int64(len(cached.body))
+
+		return resp, nil
+	}
+
+	// only API responses are cached, release assets and logs can be large
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	c.set(key, &cachedResponse{etag: etag, header: resp.Header.Clone(), body: body})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
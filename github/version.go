@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// versionConfig defines how the next version is calculated from the PR labels
+type versionConfig struct {
+	MajorLabel       string
+	MinorLabel       string
+	PatchLabel       string
+	PrereleaseLabels []string
+	// DefaultBump is the increment used when there are no version labels
+	DefaultBump string
+	// TagPrefix limits the tags to those starting with the prefix
+	TagPrefix string
+}
+
+// nextVersion returns the next version for the given PRs and repository tags, the label from the
+// PR with the highest number is used. Returns an empty string when there is no version increment.
+func nextVersion(prs []*github.PullRequest, tags []string, cfg versionConfig) (string, error) {
+	versions := parseVersions(tags, cfg.TagPrefix)
+	_, cv := latestTag(versions, cfg.TagPrefix)
+
+	bump := ""
+	channel := ""
+	maxID := 0
+
+	// check the PRs for labels
+	for _, pr := range prs {
+		log.Debug("Checking PR for labels", "pr", pr.GetNumber(), "labels", pr.Labels)
+
+		// only check the latest PR
+		if pr.GetNumber() > maxID {
+			maxID = pr.GetNumber()
+			bump, channel = prLabels(pr, cfg)
+		}
+	}
+
+	if bump == "" {
+		bump = cfg.DefaultBump
+	}
+
+	// a prerelease label without a version label is a patch release
+	if bump == "" && channel != "" {
+		bump = "patch"
+	}
+
+	log.Debug("Setting version increment", "bump", bump, "prerelease", channel)
+
+	return incrementVersion(cv, versions, bump, channel, cfg.TagPrefix)
+}
+
+// prLabels returns the highest version increment and the prerelease channel for the labels on the PR
+func prLabels(pr *github.PullRequest, cfg versionConfig) (string, string) {
+	bump := ""
+	channel := ""
+
+	// if there are multiple labels, get the highest one
+	for _, l := range pr.Labels {
+		switch l.GetName() {
+		case cfg.MajorLabel:
+			bump = "major"
+		case cfg.MinorLabel:
+			if bump != "major" {
+				bump = "minor"
+			}
+		case cfg.PatchLabel:
+			if bump == "" {
+				bump = "patch"
+			}
+		}
+
+		for _, p := range cfg.PrereleaseLabels {
+			if l.GetName() == p {
+				channel = p[strings.LastIndex(p, ":")+1:]
+			}
+		}
+	}
+
+	return bump, channel
+}
+
+// parseVersions returns the sorted semantic versions for the tags starting with prefix,
+// the prefix is removed before parsing and tags that are not semantic versions are ignored
+func parseVersions(tags []string, prefix string) []*semver.Version {
+	versions := []*semver.Version{}
+
+	for _, t := range tags {
+		if !strings.HasPrefix(t, prefix) {
+			continue
+		}
+
+		v, err := semver.NewVersion(strings.TrimPrefix(t, prefix))
+		if err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	sort.Sort(semver.Collection(versions))
+
+	return versions
+}
+
+// latestTag returns the name and version of the latest tag that is not a prerelease, when there
+// is no tag the name is empty and the version is 0.0.0
+func latestTag(versions []*semver.Version, prefix string) (string, *semver.Version) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Prerelease() == "" {
+			return prefix + versions[i].Original(), versions[i]
+		}
+	}
+
+	cv, _ := semver.NewVersion("v0.0.0")
+	return "", cv
+}
+
+// incrementVersion increments the current version by bump, if channel is set the next prerelease
+// number for the channel is added based on the existing versions
+func incrementVersion(cv *semver.Version, versions []*semver.Version, bump, channel, prefix string) (string, error) {
+	var nv semver.Version
+
+	switch bump {
+	case "major":
+		nv = cv.IncMajor()
+	case "minor":
+		nv = cv.IncMinor()
+	case "patch":
+		nv = cv.IncPatch()
+	case "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid version increment %q, must be one of major, minor or patch", bump)
+	}
+
+	if channel != "" {
+		// find the highest existing prerelease number for the version and channel
+		num := 0
+		for _, v := range versions {
+			if v.Major() != nv.Major() || v.Minor() != nv.Minor() || v.Patch() != nv.Patch() {
+				continue
+			}
+
+			c, n, ok := strings.Cut(v.Prerelease(), ".")
+			if !ok || c != channel {
+				continue
+			}
+
+			if i, err := strconv.Atoi(n); err == nil && i > num {
+				num = i
+			}
+		}
+
+		var err error
+		nv, err = nv.SetPrerelease(fmt.Sprintf("%s.%d", channel, num+1))
+		if err != nil {
+			return "", fmt.Errorf("invalid prerelease label %q: %w", channel, err)
+		}
+	}
+
+	return prefix + nv.String(), nil
+}
+
+// listPullRequestsWithCommit returns all the PRs associated with the commit
+func listPullRequestsWithCommit(ctx context.Context, client *github.Client, owner, repo, sha string) ([]*github.PullRequest, error) {
+	prs := []*github.PullRequest{}
+	page := 0
+
+	// loop through and get all prs associated with the commit, list might be paged
+	for {
+		p, resp, err := client.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull requests: %w", classifyError(err))
+		}
+
+		prs = append(prs, p...)
+
+		page = resp.NextPage
+		if page == 0 {
+			break
+		}
+	}
+
+	return prs, nil
+}
+
+// listTags returns all the tags in the repository
+func listTags(ctx context.Context, client *github.Client, owner, repo string) ([]*github.RepositoryTag, error) {
+	tags := []*github.RepositoryTag{}
+	page := 0
+
+	for {
+		t, resp, err := client.Repositories.ListTags(ctx, owner, repo, &github.ListOptions{Page: page, PerPage: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tags: %w", classifyError(err))
+		}
+
+		tags = append(tags, t...)
+
+		page = resp.NextPage
+		if page == 0 {
+			break
+		}
+	}
+
+	return tags, nil
+}
+
+// tagNames returns the names of the tags
+func tagNames(tags []*github.RepositoryTag) []string {
+	names := []string{}
+	for _, t := range tags {
+		names = append(names, t.GetName())
+	}
+
+	return names
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"main/internal/dagger"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v58/github"
+)
+
+// WorkflowRun is the result of a GitHub Actions workflow run
+type WorkflowRun struct {
+	ID         int
+	Status     string
+	Conclusion string
+	URL        string
+	// Logs contains the log files for the jobs in the run
+	Logs *dagger.Directory
+}
+
+// DispatchWorkflow triggers a workflow_dispatch event for the workflow file, i.e. `release.yaml`, on the given
+// branch or tag and waits for the workflow run to complete. Inputs are defined as `name=value`.
+// The run is polled with an increasing interval until it completes or the timeout, in seconds, expires.
+// Returns the conclusion of the run and its logs.
+func (m *Github) DispatchWorkflow(
+	ctx context.Context,
+	owner,
+	repo,
+	workflow,
+	ref string,
+	// +optional
+	inputs []string,
+	// +optional
+	// +default=1800
+	timeout int,
+) (*WorkflowRun, error) {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	in := map[string]interface{}{}
+	for _, i := range inputs {
+		k, v, ok := strings.Cut(i, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid input %q, inputs must be in the form name=value", i)
+		}
+
+		in[k] = v
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// the dispatch API does not return the run, record the existing runs so the new run can be identified
+	existing, err := listDispatchRuns(ctx, client, owner, repo, workflow, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = client.Actions.CreateWorkflowDispatchEventByFileName(ctx, owner, repo, workflow, github.CreateWorkflowDispatchEventRequest{
+		Ref:    ref,
+		Inputs: in,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to dispatch workflow: %w", classifyError(err))
+	}
+
+	log.Debug("Dispatched workflow", "workflow", workflow, "ref", ref)
+
+	var run *github.WorkflowRun
+
+	err = poll(ctx, func() (bool, error) {
+		runs, err := listDispatchRuns(ctx, client, owner, repo, workflow, ref)
+		if err != nil {
+			return false, err
+		}
+
+		// use the earliest run that did not exist before the dispatch
+		for _, r := range runs {
+			if existing[r.GetID()] == nil && (run == nil || r.GetID() < run.GetID()) {
+				run = r
+			}
+		}
+
+		return run != nil, nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workflow run: %w", err)
+	}
+
+	log.Debug("Found workflow run", "run", run.GetID(), "url", run.GetHTMLURL())
+
+	err = poll(ctx, func() (bool, error) {
+		r, _, err := client.Actions.GetWorkflowRunByID(ctx, owner, repo, run.GetID())
+		if err != nil {
+			return false, fmt.Errorf("failed to get workflow run: %w", classifyError(err))
+		}
+
+		run = r
+		log.Debug("Waiting for workflow run", "run", run.GetID(), "status", run.GetStatus())
+
+		return run.GetStatus() == "completed", nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for workflow run %d: %w", run.GetID(), err)
+	}
+
+	logs, err := downloadRunLogs(ctx, client, owner, repo, run.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkflowRun{
+		ID:         int(run.GetID()),
+		Status:     run.GetStatus(),
+		Conclusion: run.GetConclusion(),
+		URL:        run.GetHTMLURL(),
+		Logs:       logs,
+	}, nil
+}
+
+// listDispatchRuns returns the latest workflow_dispatch runs for the workflow and ref keyed by id
+func listDispatchRuns(ctx context.Context, client *github.Client, owner, repo, workflow, ref string) (map[int64]*github.WorkflowRun, error) {
+	runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, owner, repo, workflow, &github.ListWorkflowRunsOptions{
+		Event:       "workflow_dispatch",
+		Branch:      ref,
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow runs: %w", classifyError(err))
+	}
+
+	m := map[int64]*github.WorkflowRun{}
+	for _, r := range runs.WorkflowRuns {
+		m[r.GetID()] = r
+	}
+
+	return m, nil
+}
+
+// poll calls f until it returns true, an error, or the context is done. The interval between calls
+// starts at 2 seconds and increases up to 30 seconds
+func poll(ctx context.Context, f func() (bool, error)) error {
+	interval := 2 * time.Second
+
+	for {
+		done, err := f()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval = min(interval*3/2, 30*time.Second)
+	}
+}
+
+// downloadRunLogs downloads the log archive for a workflow run and returns it as a directory
+func downloadRunLogs(ctx context.Context, client *github.Client, owner, repo string, runID int64) (*dagger.Directory, error) {
+	u, _, err := client.Actions.GetWorkflowRunLogs(ctx, owner, repo, runID, 3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow run logs: %w", classifyError(err))
+	}
+
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(rq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download workflow run logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download workflow run logs, expected status 200, got %d", resp.StatusCode)
+	}
+
+	archive, err := os.CreateTemp("", "logs-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	_, err = io.Copy(archive, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download workflow run logs: %w", err)
+	}
+
+	// the archive is extracted into the module working directory as dagger can only load directories from there
+	dir, err := os.MkdirTemp(".", "workflow-logs-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	err = unzip(archive.Name(), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.CurrentModule().Workdir(dir).Sync(ctx)
+}
+
+// unzip extracts a zip archive into the destination directory
+func unzip(src, dest string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		p := filepath.Join(dest, f.Name)
+
+		// ensure files can not be written outside of the destination
+		if !strings.HasPrefix(p, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid file path in archive: %s", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			os.MkdirAll(p, 0755)
+			continue
+		}
+
+		err := os.MkdirAll(filepath.Dir(p), 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+
+		err = extractFile(f, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractFile writes a single file from a zip archive
+func extractFile(f *zip.File, dest string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s from archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+	}
+
+	return nil
+}